@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/cyberkillua/dailyread/internal/database"
+	"github.com/cyberkillua/dailyread/internal/utils"
+)
+
+func runFeeds(ctx context.Context, a *app, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: dailyread feeds <add|list|remove> [arguments]")
+	}
+
+	switch args[0] {
+	case "add":
+		return feedsAdd(ctx, a, args[1:])
+	case "list":
+		return feedsList(ctx, a)
+	case "remove":
+		return feedsRemove(ctx, a, args[1:])
+	default:
+		return fmt.Errorf("unknown feeds command %q", args[0])
+	}
+}
+
+func feedsAdd(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("feeds add", flag.ExitOnError)
+	name := flags.String("name", "", "display name of the feed")
+	feedURL := flags.String("url", "", "URL of the RSS or Atom feed")
+	feedType := flags.String("type", "rss", "type of the feed")
+	maxAgeDays := flags.Int("max-age-days", -1, "ignore items older than this many days, 0 for no limit (default INGEST_MAX_AGE_DAYS)")
+	userAgent := flags.String("user-agent", "", "User-Agent sent when fetching this feed (default FETCH_USER_AGENT)")
+	fullText := flags.Bool("full-text", false, "download each new post's article and extract its full text")
+	flags.Parse(args)
+
+	if strings.TrimSpace(*name) == "" {
+		return errors.New("-name is required")
+	}
+	if !utils.ValidFeedURL(*feedURL) {
+		return errors.New("-url must be an absolute http or https URL")
+	}
+
+	maxItemAgeDays := sql.NullInt32{}
+	if *maxAgeDays >= 0 {
+		maxItemAgeDays = sql.NullInt32{Int32: int32(*maxAgeDays), Valid: true}
+	}
+
+	webpage, err := a.db.CreateWebpage(ctx, database.CreateWebpageParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		Name:      *name,
+		Url:       *feedURL,
+		Type:      *feedType,
+
+		MaxItemAgeDays: maxItemAgeDays,
+		UserAgent:      sql.NullString{String: *userAgent, Valid: *userAgent != ""},
+		FullText:       *fullText,
+	})
+	if err != nil {
+		return fmt.Errorf("creating feed: %w", err)
+	}
+
+	fmt.Printf("added %s (%s)\n", webpage.Name, webpage.ID)
+	return nil
+}
+
+func feedsList(ctx context.Context, a *app) error {
+	webpages, err := a.db.ListWebpages(ctx)
+	if err != nil {
+		return fmt.Errorf("listing feeds: %w", err)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tTYPE\tLAST FETCHED\tBACKOFF UNTIL\tURL")
+	for _, webpage := range webpages {
+		lastFetched := "never"
+		if webpage.LastUpdatedAt.Valid {
+			lastFetched = webpage.LastUpdatedAt.Time.UTC().Format(time.RFC3339)
+		}
+		backoffUntil := "-"
+		if webpage.BackoffUntil.Valid && webpage.BackoffUntil.Time.After(time.Now()) {
+			backoffUntil = webpage.BackoffUntil.Time.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", webpage.ID, webpage.Name, webpage.Type, lastFetched, backoffUntil, webpage.Url)
+	}
+	return tw.Flush()
+}
+
+func feedsRemove(ctx context.Context, a *app, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: dailyread feeds remove <id|url>")
+	}
+
+	webpage, err := findWebpage(ctx, a.db, args[0])
+	if err != nil {
+		return err
+	}
+
+	if _, err := a.db.DeleteWebpage(ctx, webpage.ID); err != nil {
+		return fmt.Errorf("removing feed: %w", err)
+	}
+
+	fmt.Printf("removed %s (%s)\n", webpage.Name, webpage.ID)
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/cyberkillua/dailyread/internal/database"
+	"github.com/cyberkillua/dailyread/internal/utils"
+)
+
+func runImport(ctx context.Context, a *app, args []string) error {
+	if len(args) != 2 || args[0] != "opml" {
+		return errors.New("usage: dailyread import opml <file|->")
+	}
+
+	var r io.Reader = os.Stdin
+	if args[1] != "-" {
+		f, err := os.Open(args[1])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	doc, err := utils.ParseOPML(r)
+	if err != nil {
+		return err
+	}
+
+	var added, existing, invalid int
+	for _, feed := range doc.Feeds() {
+		if !utils.ValidFeedURL(feed.URL) {
+			fmt.Printf("skipped %s: invalid URL %q\n", feed.Name, feed.URL)
+			invalid++
+			continue
+		}
+
+		webpage, err := a.db.CreateWebpage(ctx, database.CreateWebpageParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+			Name:      feed.Name,
+			Url:       feed.URL,
+			Type:      "rss",
+		})
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+			// A feed already present still joins the folders it is listed
+			// in, so importing an export restores its structure.
+			webpage, err = a.db.GetWebpageByURLOrAlias(ctx, feed.URL)
+			if err != nil {
+				return fmt.Errorf("importing %s: %w", feed.URL, err)
+			}
+			existing++
+		} else if err != nil {
+			return fmt.Errorf("importing %s: %w", feed.URL, err)
+		} else {
+			added++
+		}
+
+		if len(feed.Folders) > 0 {
+			folder, err := utils.EnsureFolderPath(ctx, a.db, feed.Folders)
+			if err != nil {
+				return fmt.Errorf("creating folder %s: %w", strings.Join(feed.Folders, "/"), err)
+			}
+			err = a.db.AddWebpageToFolder(ctx, database.AddWebpageToFolderParams{
+				WebpageID: webpage.ID,
+				FolderID:  folder.ID,
+				CreatedAt: time.Now().UTC(),
+			})
+			if err != nil {
+				return fmt.Errorf("adding %s to folder: %w", feed.URL, err)
+			}
+		}
+	}
+
+	fmt.Printf("imported %d feeds, %d already present, %d invalid\n", added, existing, invalid)
+	return nil
+}
+
+func runExport(ctx context.Context, a *app, args []string) error {
+	if len(args) < 1 || len(args) > 2 || args[0] != "opml" {
+		return errors.New("usage: dailyread export opml [file|-]")
+	}
+
+	webpages, err := a.db.ListWebpages(ctx)
+	if err != nil {
+		return err
+	}
+	folders, err := a.db.ListFolders(ctx)
+	if err != nil {
+		return err
+	}
+	memberships, err := a.db.ListWebpageFolders(ctx)
+	if err != nil {
+		return err
+	}
+	doc := utils.BuildOPML("dailyRead subscriptions", webpages, folders, memberships)
+
+	if len(args) == 1 || args[1] == "-" {
+		return utils.WriteOPML(os.Stdout, doc)
+	}
+	f, err := os.Create(args[1])
+	if err != nil {
+		return err
+	}
+	if err := utils.WriteOPML(f, doc); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -1,46 +1,144 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/cyberkillua/dailyread/internal/config"
 	"github.com/cyberkillua/dailyread/internal/database"
-	"github.com/cyberkillua/dailyread/internal/server"
+	"github.com/cyberkillua/dailyread/internal/fetcher"
+	"github.com/cyberkillua/dailyread/internal/fulltext"
+	"github.com/cyberkillua/dailyread/internal/logging"
 	"github.com/cyberkillua/dailyread/internal/utils"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
+// app holds what every sub command shares.
+type app struct {
+	cfg     *config.Config
+	conn    *sql.DB
+	db      *database.Queries
+	fetcher *fetcher.Fetcher
+}
+
+// newScraper builds a scraper with the ingest settings from the config.
+func (a *app) newScraper(concurrency int, interval time.Duration) *utils.Scraper {
+	scraper := utils.NewScraper(a.conn, concurrency, interval)
+	scraper.MaxItemAge = time.Duration(a.cfg.IngestMaxAgeDays) * 24 * time.Hour
+	scraper.Fetcher = a.fetcher
+	scraper.Clusterer.Window = a.cfg.ClusterWindow
+	return scraper
+}
+
+// newFullTextWorker builds the worker that extracts the full text of posts
+// from webpages in full text mode.
+func (a *app) newFullTextWorker() *fulltext.Worker {
+	return fulltext.New(a.db, a.fetcher, a.cfg.FullTextConcurrency, a.cfg.FullTextInterval)
+}
+
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, a *app, args []string) error
+}
+
+var commands = []command{
+	{"serve", "run the HTTP API, optionally with the scraper", runServe},
+	{"worker", "run only the scraper, sharing feeds with other workers", runWorker},
+	{"scrape", "run one scrape cycle, or scrape a single feed, then exit", runScrape},
+	{"feeds", "manage feeds: add, list, remove", runFeeds},
+	{"import", "import feeds: opml <file>", runImport},
+	{"export", "export feeds: opml [file]", runExport},
+	{"migrate", "manage the database schema: up, down, status, version", runMigrate},
+}
+
 func main() {
 
 	godotenv.Load()
+
+	name := "serve"
+	args := os.Args[1:]
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" || name == "-h" || name == "--help" {
+		usage()
+		return
+	}
+
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == name {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		usage()
+		os.Exit(2)
+	}
+
 	// Load configuration
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatal("Failed to load configuration:", err)
+		fatal("Failed to load configuration", err)
 	}
 
-	// Verify critical environment variables
-	if cfg.DatabaseURL == "" {
-		log.Fatal("DATABASE_URL environment variable is not set")
-	}
+	logging.Setup(cfg)
 
 	// Database connection
 	connection, err := sql.Open("postgres", cfg.DatabaseURL)
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		fatal("Failed to connect to database", err)
 	}
+	defer connection.Close()
 
-	// Initialize database queries
-	db := database.New(connection)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	go utils.StartScrapping(db, 10, 12*time.Hour)
+	a := &app{
+		cfg:  cfg,
+		conn: connection,
+		db:   database.New(connection),
+		fetcher: fetcher.New(fetcher.Options{
+			UserAgent:       cfg.FetchUserAgent,
+			Timeout:         cfg.FetchTimeout,
+			MaxRedirects:    cfg.FetchMaxRedirects,
+			MaxConnsPerHost: cfg.FetchMaxConnsPerHost,
+			HostConcurrency: cfg.FetchHostConcurrency,
+			HostInterval:    cfg.FetchHostInterval,
+			IgnoreRobots:    !cfg.FetchRespectRobots,
+			MaxBodySize:     cfg.FeedMaxBodyBytes,
+			Proxy:           cfg.FetchProxy,
+		}),
+	}
+	if err := cmd.run(ctx, a, args); err != nil {
+		stop()
+		connection.Close()
+		fatal(fmt.Sprintf("%s failed", cmd.name), err)
+	}
+}
 
-	// Create and start server
-	srv := server.New(cfg, db)
-	if err := srv.Start(); err != nil {
-		log.Fatal("Server failed to start:", err)
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: dailyread <command> [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run without a command to serve the API (see serve -h).")
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/cyberkillua/dailyread/internal/migrate"
+)
+
+func runMigrate(ctx context.Context, a *app, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: dailyread migrate <" + strings.Join(migrate.Commands, "|") + ">")
+	}
+
+	return migrate.Run(ctx, a.conn, args[0], os.Stdout)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/cyberkillua/dailyread/internal/database"
+	"github.com/cyberkillua/dailyread/internal/utils"
+)
+
+func runScrape(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("scrape", flag.ExitOnError)
+	concurrency := flags.Int("concurrency", 10, "feeds scraped in parallel")
+	feed := flags.String("feed", "", "only scrape the feed with this id or URL")
+	force := flags.Bool("force", false, "scrape -feed even if another worker holds it or it is backing off")
+	flags.Parse(args)
+
+	if *force && *feed == "" {
+		return errors.New("-force only applies with -feed")
+	}
+
+	scraper := a.newScraper(*concurrency, 0)
+
+	var results []utils.FeedResult
+	if *feed != "" {
+		page, err := findWebpage(ctx, a.db, *feed)
+		if err != nil {
+			return err
+		}
+		result, err := scraper.ScrapeWebpageByID(ctx, page.ID, *force)
+		var busy *utils.WebpageBusyError
+		if errors.As(err, &busy) {
+			return fmt.Errorf("%w; use -force to scrape it anyway", err)
+		}
+		if err != nil {
+			return fmt.Errorf("claiming feed: %w", err)
+		}
+		results = []utils.FeedResult{result}
+		scraper.ClusterPosts(ctx)
+	} else {
+		var err error
+		results, err = scraper.ScrapeOnce(ctx)
+		if err != nil {
+			return fmt.Errorf("getting feeds to scrape: %w", err)
+		}
+	}
+
+	failed := printScrapeSummary(results)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d feeds failed", failed, len(results))
+	}
+	return nil
+}
+
+func printScrapeSummary(results []utils.FeedResult) int {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FEED\tFOUND\tCREATED\tUPDATED\tDUPLICATE\tSKIPPED\tDURATION\tERROR")
+
+	var found, created, updated, duplicates, skipped, failed int
+	for _, result := range results {
+		errText := ""
+		if result.Err != nil {
+			errText = result.Err.Error()
+			failed++
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%s\t%s\n",
+			result.Webpage.Name, result.Found, result.Created, result.Updated, result.Duplicates, result.Skipped,
+			result.Duration.Round(time.Millisecond), errText)
+
+		found += result.Found
+		created += result.Created
+		updated += result.Updated
+		duplicates += result.Duplicates
+		skipped += result.Skipped
+	}
+	fmt.Fprintf(tw, "TOTAL (%d feeds)\t%d\t%d\t%d\t%d\t%d\t\t%d failed\n", len(results), found, created, updated, duplicates, skipped, failed)
+	tw.Flush()
+
+	for _, result := range results {
+		if result.MovedTo != "" {
+			fmt.Printf("%s moved permanently: %s -> %s\n", result.Webpage.Name, result.Webpage.Url, result.MovedTo)
+		}
+	}
+
+	return failed
+}
+
+// findWebpage resolves a webpage from either its id or its feed URL,
+// current or former.
+func findWebpage(ctx context.Context, db *database.Queries, ref string) (database.Webpage, error) {
+	var page database.Webpage
+	var err error
+	if id, parseErr := uuid.Parse(ref); parseErr == nil {
+		page, err = db.GetWebpageByID(ctx, id)
+	} else {
+		page, err = db.GetWebpageByURLOrAlias(ctx, ref)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Webpage{}, fmt.Errorf("no feed matches %q", ref)
+	}
+	return page, err
+}
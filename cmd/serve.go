@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"time"
+
+	"github.com/cyberkillua/dailyread/internal/health"
+	"github.com/cyberkillua/dailyread/internal/janitor"
+	"github.com/cyberkillua/dailyread/internal/metrics"
+	"github.com/cyberkillua/dailyread/internal/migrate"
+	"github.com/cyberkillua/dailyread/internal/server"
+	"github.com/cyberkillua/dailyread/internal/tagging"
+	"github.com/cyberkillua/dailyread/internal/trending"
+)
+
+func runServe(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	withScraper := flags.Bool("scraper", a.cfg.ScraperEnabled, "run the scraper alongside the API (default from SCRAPER_ENABLED)")
+	concurrency := flags.Int("concurrency", 10, "feeds scraped in parallel per cycle")
+	interval := flags.Duration("interval", 12*time.Hour, "time between scrape cycles")
+	flags.Parse(args)
+
+	if a.cfg.Port == "" {
+		return errors.New("PORT environment variable not set")
+	}
+
+	if a.cfg.MigrateOnStart {
+		if err := migrate.Up(ctx, a.conn); err != nil {
+			return err
+		}
+	}
+
+	metrics.RegisterDBStats(a.conn)
+
+	if a.cfg.RetentionDays > 0 {
+		go janitor.New(a.conn, a.cfg.RetentionDays, a.cfg.RetentionMode, a.cfg.JanitorInterval).Start(ctx)
+	}
+	go trending.New(a.conn, a.cfg.TrendingWindow, a.cfg.TrendingInterval).Start(ctx)
+	go tagging.New(a.conn, a.cfg.TaggingInterval).Start(ctx)
+
+	var heartbeat *health.Heartbeat
+	if *withScraper {
+		heartbeat = health.NewHeartbeat()
+		scraper := a.newScraper(*concurrency, *interval)
+		scraper.Heartbeat = heartbeat
+		go scraper.StartScrapping(ctx)
+		go a.newFullTextWorker().Start(ctx)
+	}
+
+	// Create and start server
+	srv := server.New(a.cfg, a.conn, a.db, health.NewChecker(a.conn, heartbeat))
+	return srv.Start(ctx)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/cyberkillua/dailyread/internal/health"
+	"github.com/cyberkillua/dailyread/internal/janitor"
+	"github.com/cyberkillua/dailyread/internal/metrics"
+	"github.com/cyberkillua/dailyread/internal/migrate"
+	"github.com/cyberkillua/dailyread/internal/server"
+	"github.com/cyberkillua/dailyread/internal/tagging"
+	"github.com/cyberkillua/dailyread/internal/trending"
+)
+
+func runWorker(ctx context.Context, a *app, args []string) error {
+	flags := flag.NewFlagSet("worker", flag.ExitOnError)
+	concurrency := flags.Int("concurrency", 10, "feeds claimed and scraped in parallel per cycle")
+	interval := flags.Duration("interval", 12*time.Hour, "time between scrape cycles")
+	lease := flags.Duration("lease", 10*time.Minute, "how long a claimed feed stays reserved for this worker")
+	workerID := flags.String("id", "", "worker id recorded on leases (default host-pid-random)")
+	opsAddr := flags.String("ops-addr", "", "address serving /metrics, /livez and /readyz, e.g. :9090")
+	flags.Parse(args)
+
+	if a.cfg.MigrateOnStart {
+		if err := migrate.Up(ctx, a.conn); err != nil {
+			return err
+		}
+	}
+
+	metrics.RegisterDBStats(a.conn)
+
+	if a.cfg.RetentionDays > 0 {
+		go janitor.New(a.conn, a.cfg.RetentionDays, a.cfg.RetentionMode, a.cfg.JanitorInterval).Start(ctx)
+	}
+	go trending.New(a.conn, a.cfg.TrendingWindow, a.cfg.TrendingInterval).Start(ctx)
+	go tagging.New(a.conn, a.cfg.TaggingInterval).Start(ctx)
+
+	scraper := a.newScraper(*concurrency, *interval)
+	scraper.Heartbeat = health.NewHeartbeat()
+	scraper.LeaseDuration = *lease
+	if *workerID != "" {
+		scraper.WorkerID = *workerID
+	}
+
+	if *opsAddr != "" {
+		opsServer := &http.Server{
+			Addr:    *opsAddr,
+			Handler: server.NewOpsHandler(health.NewChecker(a.conn, scraper.Heartbeat)),
+		}
+		go func() {
+			slog.Info("Ops server listening", "addr", *opsAddr)
+			if err := opsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("Ops server failed", "error", err)
+			}
+		}()
+		defer opsServer.Close()
+	}
+
+	go a.newFullTextWorker().Start(ctx)
+	scraper.StartScrapping(ctx)
+	return nil
+}
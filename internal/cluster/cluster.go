@@ -0,0 +1,219 @@
+// Package cluster groups posts from different sources that cover the same
+// story, so listings can show the story once.
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/cyberkillua/dailyread/internal/database"
+)
+
+// lockID is the Postgres advisory lock that keeps two processes from
+// clustering at the same time.
+const lockID = 0x6461696c7903
+
+// Clusterer assigns every new post to a cluster. A post joins the cluster
+// of an earlier post with the same normalized URL, or of a post from
+// another source published within Window whose title fingerprint differs
+// in at most MaxDistance bits; otherwise it starts a cluster of its own.
+type Clusterer struct {
+	Conn *sql.DB
+	// Window is how far apart in time two posts can be and still cover
+	// the same story.
+	Window time.Duration
+	// MaxDistance is the largest number of differing SimHash bits for two
+	// titles to count as the same story.
+	MaxDistance int
+	// MinTokens is the fewest meaningful words a title needs to be
+	// compared; shorter titles only cluster by URL.
+	MinTokens int
+	// BatchSize bounds the posts clustered per statement.
+	BatchSize int32
+}
+
+func New(conn *sql.DB, window time.Duration) *Clusterer {
+	return &Clusterer{
+		Conn:        conn,
+		Window:      window,
+		MaxDistance: 10,
+		MinTokens:   3,
+		BatchSize:   500,
+	}
+}
+
+// candidate is a clustered post a new post may join.
+type candidate struct {
+	id        uuid.UUID
+	webpageID uuid.NullUUID
+	clusterID uuid.UUID
+	// simhash is zero for titles too short to compare.
+	simhash uint64
+	urlKey  string
+	sortAt  time.Time
+}
+
+// RunOnce clusters every post that has not been clustered yet and returns
+// how many there were. It returns without doing anything if another
+// process holds the cluster lock.
+func (c *Clusterer) RunOnce(ctx context.Context) (int, error) {
+	conn, err := c.Conn.Conn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("acquiring connection: %w", err)
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", lockID).Scan(&locked); err != nil {
+		return 0, fmt.Errorf("acquiring cluster lock: %w", err)
+	}
+	if !locked {
+		slog.Debug("Clustering skipped, another process holds the lock")
+		return 0, nil
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID)
+
+	start := time.Now()
+	db := database.New(conn)
+
+	clustered, joined := 0, 0
+	for ctx.Err() == nil {
+		posts, err := db.ListUnclusteredPosts(ctx, c.BatchSize)
+		if err != nil {
+			return clustered, fmt.Errorf("listing unclustered posts: %w", err)
+		}
+		if len(posts) == 0 {
+			break
+		}
+
+		n, err := c.clusterBatch(ctx, db, posts)
+		if err != nil {
+			return clustered, err
+		}
+		clustered += len(posts)
+		joined += n
+
+		if len(posts) < int(c.BatchSize) {
+			break
+		}
+	}
+
+	if clustered > 0 {
+		slog.Info("Clustered posts",
+			"posts", clustered,
+			"joined_existing", joined,
+			"duration", time.Since(start),
+		)
+	}
+	return clustered, nil
+}
+
+// clusterBatch stores a cluster for each of posts and returns how many
+// joined a cluster instead of starting one.
+func (c *Clusterer) clusterBatch(ctx context.Context, db *database.Queries, posts []database.ListUnclusteredPostsRow) (int, error) {
+	from, to := posts[0].SortAt, posts[0].SortAt
+	urlKeys := make([]string, len(posts))
+	for i, post := range posts {
+		if post.SortAt.Before(from) {
+			from = post.SortAt
+		}
+		if post.SortAt.After(to) {
+			to = post.SortAt
+		}
+		urlKeys[i] = NormalizeURL(post.Url)
+	}
+
+	rows, err := db.GetClusterCandidates(ctx, database.GetClusterCandidatesParams{
+		FromAt:  from.Add(-c.Window),
+		ToAt:    to.Add(c.Window),
+		UrlKeys: urlKeys,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("loading cluster candidates: %w", err)
+	}
+	candidates := make([]candidate, 0, len(rows)+len(posts))
+	for _, row := range rows {
+		candidates = append(candidates, candidate{
+			id:        row.ID,
+			webpageID: row.WebpageID,
+			clusterID: row.ClusterID.UUID,
+			simhash:   uint64(row.Simhash.Int64),
+			urlKey:    row.UrlKey.String,
+			sortAt:    row.SortAt,
+		})
+	}
+
+	params, joined := c.assign(posts, urlKeys, candidates)
+	if err := db.SetPostClusters(ctx, params); err != nil {
+		return 0, fmt.Errorf("storing clusters: %w", err)
+	}
+	return joined, nil
+}
+
+// assign picks a cluster for each of posts from candidates, in order, and
+// returns how many joined a cluster instead of starting one.
+func (c *Clusterer) assign(posts []database.ListUnclusteredPostsRow, urlKeys []string, candidates []candidate) (database.SetPostClustersParams, int) {
+	params := database.SetPostClustersParams{
+		Ids:        make([]uuid.UUID, len(posts)),
+		ClusterIds: make([]uuid.UUID, len(posts)),
+		Simhashes:  make([]int64, len(posts)),
+		UrlKeys:    urlKeys,
+	}
+	joined := 0
+	for i, post := range posts {
+		next := candidate{
+			id:        post.ID,
+			webpageID: post.WebpageID,
+			clusterID: post.ID,
+			urlKey:    urlKeys[i],
+			sortAt:    post.SortAt,
+		}
+		if tokens := Tokens(post.Title); len(tokens) >= c.MinTokens {
+			next.simhash = SimHash(tokens)
+		}
+
+		if match, ok := c.match(next, candidates); ok {
+			next.clusterID = match.clusterID
+			joined++
+		}
+		// Later posts in the batch can join this one.
+		candidates = append(candidates, next)
+
+		params.Ids[i] = next.id
+		params.ClusterIds[i] = next.clusterID
+		params.Simhashes[i] = int64(next.simhash)
+	}
+	return params, joined
+}
+
+// match returns a candidate covering the same story as post, preferring a
+// URL match and otherwise the closest title.
+func (c *Clusterer) match(post candidate, candidates []candidate) (candidate, bool) {
+	var best candidate
+	bestDistance := c.MaxDistance + 1
+	for _, other := range candidates {
+		if other.urlKey == post.urlKey {
+			return other, true
+		}
+		if post.simhash == 0 || other.simhash == 0 {
+			continue
+		}
+		// A source repeating itself is a series, not the same story told
+		// twice.
+		if post.webpageID.Valid && post.webpageID == other.webpageID {
+			continue
+		}
+		if gap := post.sortAt.Sub(other.sortAt).Abs(); gap > c.Window {
+			continue
+		}
+		if d := Distance(post.simhash, other.simhash); d < bestDistance {
+			best, bestDistance = other, d
+		}
+	}
+	return best, bestDistance <= c.MaxDistance
+}
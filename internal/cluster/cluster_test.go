@@ -0,0 +1,223 @@
+package cluster
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/cyberkillua/dailyread/internal/database"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"plain", "https://example.com/story", "example.com/story"},
+		{"scheme dropped", "http://example.com/story", "example.com/story"},
+		{"www dropped", "https://www.example.com/story", "example.com/story"},
+		{"host lower cased", "https://Example.COM/story", "example.com/story"},
+		{"path case kept", "https://example.com/Story", "example.com/Story"},
+		{"trailing slash", "https://example.com/story/", "example.com/story"},
+		{"root", "https://example.com/", "example.com"},
+		{"fragment", "https://example.com/story#comments", "example.com/story"},
+		{"port dropped", "https://example.com:443/story", "example.com/story"},
+		{"utm parameters", "https://example.com/story?utm_source=rss&utm_medium=feed", "example.com/story"},
+		{"upper case utm", "https://example.com/story?UTM_Campaign=x", "example.com/story"},
+		{"click ids", "https://example.com/story?fbclid=abc&gclid=def", "example.com/story"},
+		{"mailchimp and ref", "https://example.com/story?mc_cid=1&mc_eid=2&ref=hn&ref_src=twsrc&source=feed", "example.com/story"},
+		{"meaningful parameters kept", "https://example.com/article?id=42&utm_source=rss", "example.com/article?id=42"},
+		{"parameters sorted", "https://example.com/search?q=go&page=2", "example.com/search?page=2&q=go"},
+		{"surrounding space", "  https://example.com/story  ", "example.com/story"},
+		{"escaped path", "https://example.com/caf%C3%A9/", "example.com/caf%C3%A9"},
+		{"not a url", "Not A URL", "not a url"},
+		{"relative", "/story", "/story"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeURL(tt.raw); got != tt.want {
+				t.Errorf("NormalizeURL(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokens(t *testing.T) {
+	tests := []struct {
+		title string
+		want  []string
+	}{
+		{"The Quick Brown Fox", []string{"quick", "brown", "fox"}},
+		{"Apple unveils the new iPhone 16, with a faster chip!", []string{"apple", "unveils", "new", "iphone", "16", "faster", "chip"}},
+		{"Node.js 22 is out.", []string{"node.js", "22", "out"}},
+		{"Crème brûlée — a how-to", []string{"crème", "brûlée", "how"}},
+		{"...", nil},
+		{"the and of", nil},
+	}
+	for _, tt := range tests {
+		if got := Tokens(tt.title); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("Tokens(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestSimHash(t *testing.T) {
+	hash := func(title string) uint64 { return SimHash(Tokens(title)) }
+
+	same := []struct{ a, b string }{
+		{"Apple unveils new iPhone 16 with faster chip", "Apple unveils the new iPhone 16 with a faster chip"},
+		{"Apple unveils new iPhone 16 with faster chip", "APPLE UNVEILS NEW IPHONE 16 WITH FASTER CHIP"},
+		{"Apple unveils new iPhone 16 with faster chip", "Apple unveils new iPhone 16, with faster chip."},
+	}
+	for _, tt := range same {
+		if d := Distance(hash(tt.a), hash(tt.b)); d != 0 {
+			t.Errorf("distance(%q, %q) = %d, want 0", tt.a, tt.b, d)
+		}
+	}
+
+	// Unrelated stories must stay well clear of the default threshold.
+	different := []struct{ a, b string }{
+		{"Apple unveils new iPhone 16 with faster chip", "Central bank raises interest rates again"},
+		{"Apple unveils new iPhone 16 with faster chip", "Storm floods coastal towns as residents evacuate"},
+		{"Central bank raises interest rates again", "Storm floods coastal towns as residents evacuate"},
+	}
+	maxDistance := New(nil, time.Hour).MaxDistance
+	for _, tt := range different {
+		if d := Distance(hash(tt.a), hash(tt.b)); d <= 2*maxDistance {
+			t.Errorf("distance(%q, %q) = %d, want more than %d", tt.a, tt.b, d, 2*maxDistance)
+		}
+	}
+
+	if SimHash(nil) != 0 {
+		t.Error("SimHash of no tokens is not zero")
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b uint64
+		want int
+	}{
+		{0, 0, 0},
+		{0b1011, 0b1011, 0},
+		{0b1011, 0b0011, 1},
+		{0, ^uint64(0), 64},
+	}
+	for _, tt := range tests {
+		if got := Distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("Distance(%b, %b) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	c := &Clusterer{Window: 48 * time.Hour, MaxDistance: 3}
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	sourceA := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+	sourceB := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+
+	post := candidate{id: uuid.New(), webpageID: sourceA, simhash: 0xff, urlKey: "example.com/new", sortAt: now}
+	other := func(webpageID uuid.NullUUID, simhash uint64, urlKey string, sortAt time.Time) candidate {
+		return candidate{id: uuid.New(), clusterID: uuid.New(), webpageID: webpageID, simhash: simhash, urlKey: urlKey, sortAt: sortAt}
+	}
+
+	tests := []struct {
+		name       string
+		post       candidate
+		candidates []candidate
+		want       int
+	}{
+		{"no candidates", post, nil, -1},
+		{"same url from the same source", post, []candidate{other(sourceA, 0, "example.com/new", now.Add(-240*time.Hour))}, 0},
+		{"same url outside the window", post, []candidate{other(sourceB, 0, "example.com/new", now.Add(-240*time.Hour))}, 0},
+		{"close title from another source", post, []candidate{other(sourceB, 0xfe, "other.com/a", now)}, 0},
+		{"title at the threshold", post, []candidate{other(sourceB, 0xf8, "other.com/a", now)}, 0},
+		{"title past the threshold", post, []candidate{other(sourceB, 0xf0, "other.com/a", now)}, -1},
+		{"close title from the same source", post, []candidate{other(sourceA, 0xff, "example.com/old", now)}, -1},
+		{"close title without a source", candidate{simhash: 0xff, urlKey: "x/1", sortAt: now}, []candidate{other(uuid.NullUUID{}, 0xff, "x/2", now)}, 0},
+		{"close title at the window edge", post, []candidate{other(sourceB, 0xff, "other.com/a", now.Add(-48*time.Hour))}, 0},
+		{"close title outside the window", post, []candidate{other(sourceB, 0xff, "other.com/a", now.Add(-49*time.Hour))}, -1},
+		{"close title later than the post", post, []candidate{other(sourceB, 0xff, "other.com/a", now.Add(time.Hour))}, 0},
+		{"short title only matches by url", candidate{webpageID: sourceA, urlKey: "example.com/new", sortAt: now}, []candidate{other(sourceB, 0, "other.com/a", now)}, -1},
+		{"candidate with short title", post, []candidate{other(sourceB, 0, "other.com/a", now)}, -1},
+		{"closest title wins", post, []candidate{
+			other(sourceB, 0xf8, "other.com/a", now),
+			other(sourceB, 0xfe, "other.com/b", now),
+			other(sourceB, 0xfc, "other.com/c", now),
+		}, 1},
+		{"url beats a closer title", post, []candidate{
+			other(sourceB, 0xff, "other.com/a", now),
+			other(sourceB, 0, "example.com/new", now),
+		}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := c.match(tt.post, tt.candidates)
+			if tt.want < 0 {
+				if ok {
+					t.Errorf("matched %+v, want no match", got)
+				}
+				return
+			}
+			if !ok || got.id != tt.candidates[tt.want].id {
+				t.Errorf("match = %+v, %v, want candidate %d", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssign(t *testing.T) {
+	c := New(nil, 48*time.Hour)
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	sourceA := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+	sourceB := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+	sourceC := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+
+	existing := candidate{
+		id:        uuid.New(),
+		clusterID: uuid.New(),
+		webpageID: sourceC,
+		urlKey:    "news.example/rates",
+		sortAt:    now.Add(-time.Hour),
+	}
+	posts := []database.ListUnclusteredPostsRow{
+		// Starts a cluster of its own.
+		{ID: uuid.New(), WebpageID: sourceA, Title: "Apple unveils new iPhone 16 with faster chip", Url: "https://a.example/iphone", SortAt: now},
+		// Joins the post above, from the same batch, by title.
+		{ID: uuid.New(), WebpageID: sourceB, Title: "Apple unveils the new iPhone 16 with a faster chip", Url: "https://b.example/apple", SortAt: now},
+		// Joins the stored post by URL.
+		{ID: uuid.New(), WebpageID: sourceA, Title: "Rates", Url: "https://www.news.example/rates/?utm_source=rss", SortAt: now},
+		// The same title again from source A is a series, but the same
+		// story from B is already clustered, so it joins that.
+		{ID: uuid.New(), WebpageID: sourceA, Title: "Apple unveils new iPhone 16 with faster chip", Url: "https://a.example/iphone-2", SortAt: now},
+		// Too short to compare and no URL match.
+		{ID: uuid.New(), WebpageID: sourceB, Title: "Live", Url: "https://b.example/live", SortAt: now},
+	}
+	urlKeys := make([]string, len(posts))
+	for i, post := range posts {
+		urlKeys[i] = NormalizeURL(post.Url)
+	}
+
+	params, joined := c.assign(posts, urlKeys, []candidate{existing})
+
+	want := []uuid.UUID{posts[0].ID, posts[0].ID, existing.clusterID, posts[0].ID, posts[4].ID}
+	if !reflect.DeepEqual(params.ClusterIds, want) {
+		t.Errorf("cluster ids = %v, want %v", params.ClusterIds, want)
+	}
+	if joined != 3 {
+		t.Errorf("joined = %d, want 3", joined)
+	}
+	if params.Simhashes[2] != 0 || params.Simhashes[4] != 0 {
+		t.Errorf("short titles got fingerprints: %v", params.Simhashes)
+	}
+	if params.Simhashes[0] == 0 || params.Simhashes[0] != params.Simhashes[1] {
+		t.Errorf("simhashes = %v, want the first two equal and non-zero", params.Simhashes)
+	}
+	if !reflect.DeepEqual(params.UrlKeys, urlKeys) {
+		t.Errorf("url keys = %v", params.UrlKeys)
+	}
+}
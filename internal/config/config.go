@@ -2,28 +2,281 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
+	// Port is only required by commands that serve HTTP.
 	Port        string
 	DatabaseURL string
-	// Add other configuration parameters as needed
+	// PublicURL is the externally visible base URL used in generated feeds.
+	// When empty it is derived from the incoming request.
+	PublicURL string
+	// LogLevel is the minimum level that is logged.
+	LogLevel slog.Level
+	// LogFormat is either "text" or "json".
+	LogFormat string
+	// MigrateOnStart applies pending database migrations before serving.
+	MigrateOnStart bool
+	// ScraperEnabled runs the scraper inside the API process. Disable it
+	// when scraping is handled by separate worker processes.
+	ScraperEnabled bool
+	// IngestMaxAgeDays drops feed items published longer ago than this when
+	// a webpage has no cutoff of its own. Zero ingests items of any age.
+	IngestMaxAgeDays int
+	// RetentionDays removes unstarred posts older than this. Zero keeps
+	// posts forever.
+	RetentionDays int
+	// RetentionMode is "delete" or "archive". Archived posts are moved to
+	// the posts_archive table instead of being deleted.
+	RetentionMode string
+	// JanitorInterval is the time between retention runs.
+	JanitorInterval time.Duration
+	// FeedMaxBodyBytes is the largest feed, after decompression, that the
+	// scraper reads.
+	FeedMaxBodyBytes int64
+	// FetchUserAgent is sent with outbound requests unless a webpage sets
+	// its own. Empty selects the fetcher's default.
+	FetchUserAgent string
+	// FetchTimeout bounds each outbound request.
+	FetchTimeout time.Duration
+	// FetchMaxRedirects is the number of redirects followed per request.
+	FetchMaxRedirects int
+	// FetchMaxConnsPerHost limits concurrent connections to one host.
+	FetchMaxConnsPerHost int
+	// FetchHostConcurrency limits concurrent requests to one host, however
+	// many feeds it serves.
+	FetchHostConcurrency int
+	// FetchHostInterval is the minimum time between two requests to the
+	// same host.
+	FetchHostInterval time.Duration
+	// FetchRespectRobots skips URLs that the host's robots.txt disallows.
+	FetchRespectRobots bool
+	// FetchProxy routes outbound requests through a proxy. When nil the
+	// standard HTTP_PROXY, HTTPS_PROXY and NO_PROXY variables apply.
+	FetchProxy *url.URL
+	// FullTextInterval is the time between checks for posts whose full
+	// text should be extracted.
+	FullTextInterval time.Duration
+	// FullTextConcurrency is the number of articles fetched in parallel
+	// for full text extraction.
+	FullTextConcurrency int
+	// TrendingWindow is how far back posts are ranked for the trending
+	// listing.
+	TrendingWindow time.Duration
+	// TrendingInterval is the time between trending score updates.
+	TrendingInterval time.Duration
+	// ClusterWindow is how far apart two posts can be published and still
+	// be grouped as the same story.
+	ClusterWindow time.Duration
+	// TaggingInterval is the time between runs of the tagger.
+	TaggingInterval time.Duration
 }
 
 func LoadConfig() (*Config, error) {
-	portString := os.Getenv("PORT")
-	if portString == "" {
-		return nil, fmt.Errorf("PORT environment variable not set")
-	}
-
 	dbUrl := os.Getenv("DATABASE_URL")
 	if dbUrl == "" {
 		return nil, fmt.Errorf("DATABASE_URL environment variable not set")
 	}
 
+	var logLevel slog.Level
+	if level := os.Getenv("LOG_LEVEL"); level != "" {
+		if err := logLevel.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("invalid LOG_LEVEL %q: %w", level, err)
+		}
+	}
+
+	logFormat := strings.ToLower(os.Getenv("LOG_FORMAT"))
+	switch logFormat {
+	case "":
+		logFormat = "text"
+	case "text", "json":
+	default:
+		return nil, fmt.Errorf("invalid LOG_FORMAT %q: must be text or json", logFormat)
+	}
+
+	migrateOnStart, err := boolEnv("MIGRATE_ON_START", false)
+	if err != nil {
+		return nil, err
+	}
+
+	scraperEnabled, err := boolEnv("SCRAPER_ENABLED", true)
+	if err != nil {
+		return nil, err
+	}
+
+	ingestMaxAgeDays, err := intEnv("INGEST_MAX_AGE_DAYS", 60)
+	if err != nil {
+		return nil, err
+	}
+
+	retentionDays, err := intEnv("RETENTION_DAYS", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	retentionMode := strings.ToLower(os.Getenv("RETENTION_MODE"))
+	switch retentionMode {
+	case "":
+		retentionMode = "delete"
+	case "delete", "archive":
+	default:
+		return nil, fmt.Errorf("invalid RETENTION_MODE %q: must be delete or archive", retentionMode)
+	}
+
+	janitorInterval, err := durationEnv("JANITOR_INTERVAL", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	feedMaxBodyBytes, err := intEnv("FEED_MAX_BODY_BYTES", 10<<20)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchTimeout, err := durationEnv("FETCH_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchMaxRedirects, err := intEnv("FETCH_MAX_REDIRECTS", 5)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchMaxConnsPerHost, err := intEnv("FETCH_MAX_CONNS_PER_HOST", 4)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchHostConcurrency, err := intEnv("FETCH_HOST_CONCURRENCY", 2)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchHostInterval, err := durationEnv("FETCH_HOST_INTERVAL", time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchRespectRobots, err := boolEnv("FETCH_RESPECT_ROBOTS", true)
+	if err != nil {
+		return nil, err
+	}
+
+	var fetchProxy *url.URL
+	if proxy := os.Getenv("FETCH_PROXY"); proxy != "" {
+		fetchProxy, err = url.Parse(proxy)
+		if err != nil || fetchProxy.Scheme == "" || fetchProxy.Host == "" {
+			return nil, fmt.Errorf("invalid FETCH_PROXY %q: must be an absolute URL", proxy)
+		}
+	}
+
+	fullTextInterval, err := durationEnv("FULLTEXT_INTERVAL", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	fullTextConcurrency, err := intEnv("FULLTEXT_CONCURRENCY", 4)
+	if err != nil {
+		return nil, err
+	}
+	if fullTextConcurrency == 0 {
+		return nil, fmt.Errorf("invalid FULLTEXT_CONCURRENCY %q: must be at least 1", os.Getenv("FULLTEXT_CONCURRENCY"))
+	}
+
+	trendingWindow, err := durationEnv("TRENDING_WINDOW", 72*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	trendingInterval, err := durationEnv("TRENDING_INTERVAL", 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterWindow, err := durationEnv("CLUSTER_WINDOW", 48*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	taggingInterval, err := durationEnv("TAGGING_INTERVAL", 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
-		Port:        portString,
+		Port:        os.Getenv("PORT"),
 		DatabaseURL: dbUrl,
+		PublicURL:   strings.TrimRight(os.Getenv("PUBLIC_URL"), "/"),
+		LogLevel:    logLevel,
+		LogFormat:   logFormat,
+
+		MigrateOnStart: migrateOnStart,
+		ScraperEnabled: scraperEnabled,
+
+		IngestMaxAgeDays: ingestMaxAgeDays,
+		RetentionDays:    retentionDays,
+		RetentionMode:    retentionMode,
+		JanitorInterval:  janitorInterval,
+
+		FeedMaxBodyBytes:     int64(feedMaxBodyBytes),
+		FetchUserAgent:       os.Getenv("FETCH_USER_AGENT"),
+		FetchTimeout:         fetchTimeout,
+		FetchMaxRedirects:    fetchMaxRedirects,
+		FetchMaxConnsPerHost: fetchMaxConnsPerHost,
+		FetchHostConcurrency: fetchHostConcurrency,
+		FetchHostInterval:    fetchHostInterval,
+		FetchRespectRobots:   fetchRespectRobots,
+		FetchProxy:           fetchProxy,
+
+		FullTextInterval:    fullTextInterval,
+		FullTextConcurrency: fullTextConcurrency,
+
+		TrendingWindow:   trendingWindow,
+		TrendingInterval: trendingInterval,
+		ClusterWindow:    clusterWindow,
+		TaggingInterval:  taggingInterval,
 	}, nil
 }
+
+func boolEnv(name string, fallback bool) (bool, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q: %w", name, value, err)
+	}
+	return parsed, nil
+}
+
+func intEnv(name string, fallback int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a non-negative integer", name, value)
+	}
+	return parsed, nil
+}
+
+func durationEnv(name string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a positive duration", name, value)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,509 @@
+// Package content turns article pages and feed markup into something safe
+// to show in a reader: the main content of a page is extracted, markup is
+// reduced to an allowlist and plain text is derived from it.
+package content
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// minWords is the shortest text Extract accepts as an article. Anything
+// shorter is more likely a consent wall or an error page.
+const minWords = 30
+
+// ErrNoContent reports a page in which no article could be found.
+var ErrNoContent = errors.New("no readable content found")
+
+// Article is the main content of a page.
+type Article struct {
+	// HTML is the sanitized content.
+	HTML string
+	// Text is HTML as plain text.
+	Text      string
+	WordCount int
+}
+
+// The class and id patterns below follow Mozilla's Readability.
+var (
+	unlikelyCandidate = regexp.MustCompile(`(?i)-ad-|ai2html|banner|breadcrumbs|combx|comment|community|cookie|cover-wrap|disqus|extra|footer|gdpr|header|legends|menu|newsletter|pager|pagination|popup|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|supplemental|yom-remote`)
+	maybeCandidate    = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveWeight    = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story`)
+	negativeWeight    = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+	sentenceEnd       = regexp.MustCompile(`\.( |$)`)
+)
+
+// removedElements never hold article content.
+var removedElements = map[atom.Atom]bool{
+	atom.Aside:    true,
+	atom.Button:   true,
+	atom.Footer:   true,
+	atom.Form:     true,
+	atom.Iframe:   true,
+	atom.Input:    true,
+	atom.Link:     true,
+	atom.Meta:     true,
+	atom.Nav:      true,
+	atom.Noscript: true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Script:   true,
+	atom.Select:   true,
+	atom.Style:    true,
+	atom.Svg:      true,
+	atom.Template: true,
+	atom.Textarea: true,
+}
+
+// removedRoles mark landmarks that are not the article.
+var removedRoles = map[string]bool{
+	"banner":        true,
+	"complementary": true,
+	"contentinfo":   true,
+	"dialog":        true,
+	"menu":          true,
+	"menubar":       true,
+	"navigation":    true,
+}
+
+// paragraphElements are the elements whose text is scored.
+var paragraphElements = map[atom.Atom]bool{
+	atom.P:          true,
+	atom.Pre:        true,
+	atom.Td:         true,
+	atom.Blockquote: true,
+}
+
+// divBlockElements are the children that stop a <div> from being scored as
+// if it were a paragraph.
+var divBlockElements = map[atom.Atom]bool{
+	atom.Blockquote: true,
+	atom.Div:        true,
+	atom.Dl:         true,
+	atom.Img:        true,
+	atom.Ol:         true,
+	atom.P:          true,
+	atom.Pre:        true,
+	atom.Section:    true,
+	atom.Table:      true,
+	atom.Ul:         true,
+}
+
+// Extract finds the main content of an HTML page, in the manner of
+// Readability: text-heavy paragraphs score their ancestors, the best
+// scoring element is taken along with related siblings, and boilerplate
+// inside it is removed. contentType selects the character set when the
+// page does not declare one, and pageURL is the base for relative links.
+func Extract(r io.Reader, contentType, pageURL string) (Article, error) {
+	r, err := charset.NewReader(r, contentType)
+	if errors.Is(err, io.EOF) {
+		// An empty page has no article, rather than failing to decode.
+		return Article{}, ErrNoContent
+	}
+	if err != nil {
+		return Article{}, fmt.Errorf("failed to decode page: %w", err)
+	}
+	doc, err := html.Parse(r)
+	if err != nil {
+		return Article{}, fmt.Errorf("failed to parse page: %w", err)
+	}
+
+	baseURL := documentBase(doc, pageURL)
+	removeBoilerplate(doc)
+
+	s := &scorer{scores: make(map[*html.Node]float64)}
+	s.scoreParagraphs(doc)
+	top := s.top()
+	if top == nil {
+		return Article{}, ErrNoContent
+	}
+
+	var buf bytes.Buffer
+	for _, n := range s.withSiblings(top) {
+		cleanConditionally(n)
+		if err := html.Render(&buf, n); err != nil {
+			return Article{}, fmt.Errorf("failed to render content: %w", err)
+		}
+	}
+
+	article := Article{HTML: Sanitize(buf.String(), baseURL)}
+	article.Text = PlainText(article.HTML)
+	article.WordCount = WordCount(article.Text)
+	if article.WordCount < minWords {
+		return Article{}, ErrNoContent
+	}
+	return article, nil
+}
+
+// documentBase applies the page's <base href>, if any, to pageURL.
+func documentBase(doc *html.Node, pageURL string) string {
+	base := findElement(doc, atom.Base)
+	if base == nil {
+		return pageURL
+	}
+	href := attr(base, "href")
+	if href == "" {
+		return pageURL
+	}
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return pageURL
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return pageURL
+	}
+	return page.ResolveReference(ref).String()
+}
+
+// removeBoilerplate strips scripts, navigation, hidden elements and
+// elements whose class or id marks them as unlikely to be content.
+func removeBoilerplate(doc *html.Node) {
+	var remove []*html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.CommentNode {
+			remove = append(remove, n)
+			return
+		}
+		if n.Type == html.ElementNode && isBoilerplate(n) {
+			remove = append(remove, n)
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	for _, n := range remove {
+		n.Parent.RemoveChild(n)
+	}
+}
+
+func isBoilerplate(n *html.Node) bool {
+	if removedElements[n.DataAtom] || removedRoles[attr(n, "role")] {
+		return true
+	}
+	if hasAttr(n, "hidden") || attr(n, "aria-hidden") == "true" {
+		return true
+	}
+	if style := strings.ReplaceAll(strings.ToLower(attr(n, "style")), " ", ""); strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") {
+		return true
+	}
+
+	switch n.DataAtom {
+	case atom.Html, atom.Body, atom.Article, atom.Main, atom.A:
+		return false
+	}
+	match := attr(n, "class") + " " + attr(n, "id")
+	return unlikelyCandidate.MatchString(match) && !maybeCandidate.MatchString(match)
+}
+
+type scorer struct {
+	scores map[*html.Node]float64
+	// order keeps the candidates in document order so that ties are broken
+	// the same way every time.
+	order []*html.Node
+}
+
+// scoreParagraphs gives every paragraph of reasonable length a score from
+// its commas and length, and adds it to the paragraph's parent, half of it
+// to the grandparent and a sixth to the great-grandparent.
+func (s *scorer) scoreParagraphs(doc *html.Node) {
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if n.Type != html.ElementNode {
+			return
+		}
+		if !paragraphElements[n.DataAtom] && (n.DataAtom != atom.Div || hasBlockChildren(n)) {
+			return
+		}
+
+		text := innerText(n)
+		length := utf8.RuneCountInString(text)
+		if length < 25 {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(length/100), 3)
+
+		level := 0
+		for ancestor := n.Parent; ancestor != nil && level < 3; ancestor = ancestor.Parent {
+			if ancestor.Type != html.ElementNode || ancestor.Parent == nil {
+				break
+			}
+			s.candidate(ancestor)
+			switch level {
+			case 0:
+				s.scores[ancestor] += score
+			case 1:
+				s.scores[ancestor] += score / 2
+			default:
+				s.scores[ancestor] += score / float64(level*3)
+			}
+			level++
+		}
+	}
+	walk(doc)
+
+	// Text that is mostly links is navigation, however long it is.
+	for _, n := range s.order {
+		s.scores[n] *= 1 - linkDensity(n)
+	}
+}
+
+func (s *scorer) candidate(n *html.Node) {
+	if _, ok := s.scores[n]; ok {
+		return
+	}
+	s.scores[n] = initialScore(n)
+	s.order = append(s.order, n)
+}
+
+func (s *scorer) top() *html.Node {
+	var top *html.Node
+	for _, n := range s.order {
+		if top == nil || s.scores[n] > s.scores[top] {
+			top = n
+		}
+	}
+	return top
+}
+
+// withSiblings returns top together with the siblings that look like part
+// of the same article, such as a lead paragraph outside the main container.
+func (s *scorer) withSiblings(top *html.Node) []*html.Node {
+	if top.Parent == nil {
+		return []*html.Node{top}
+	}
+
+	topScore := s.scores[top]
+	threshold := math.Max(10, topScore*0.2)
+	topClass := attr(top, "class")
+
+	var nodes []*html.Node
+	for sibling := top.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+		if sibling.Type != html.ElementNode {
+			continue
+		}
+		if sibling == top {
+			nodes = append(nodes, sibling)
+			continue
+		}
+
+		bonus := 0.0
+		if topClass != "" && attr(sibling, "class") == topClass {
+			bonus = topScore * 0.2
+		}
+		if score, ok := s.scores[sibling]; ok && score+bonus >= threshold {
+			nodes = append(nodes, sibling)
+			continue
+		}
+
+		if sibling.DataAtom == atom.P {
+			text := innerText(sibling)
+			length := utf8.RuneCountInString(text)
+			density := linkDensity(sibling)
+			if (length > 80 && density < 0.25) || (length > 0 && density == 0 && sentenceEnd.MatchString(text)) {
+				nodes = append(nodes, sibling)
+			}
+		}
+	}
+	return nodes
+}
+
+// initialScore favours elements that usually wrap articles and penalises
+// lists, headings and forms.
+func initialScore(n *html.Node) float64 {
+	score := classWeight(n)
+	switch n.DataAtom {
+	case atom.Div, atom.Article, atom.Main:
+		score += 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score += 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+		score -= 3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score -= 5
+	}
+	return score
+}
+
+// classWeight scores an element's class and id for words that suggest
+// content or boilerplate.
+func classWeight(n *html.Node) float64 {
+	weight := 0.0
+	for _, value := range []string{attr(n, "class"), attr(n, "id")} {
+		if value == "" {
+			continue
+		}
+		if negativeWeight.MatchString(value) {
+			weight -= 25
+		}
+		if positiveWeight.MatchString(value) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// cleanConditionally removes the blocks inside n that look like link
+// lists, share bars or other leftovers rather than part of the article.
+func cleanConditionally(n *html.Node) {
+	var remove []*html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			if isClutter(c) {
+				remove = append(remove, c)
+				continue
+			}
+			walk(c)
+		}
+	}
+	walk(n)
+
+	for _, c := range remove {
+		c.Parent.RemoveChild(c)
+	}
+}
+
+func isClutter(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Div, atom.Section, atom.Table, atom.Ul, atom.Ol:
+	default:
+		return false
+	}
+
+	weight := classWeight(n)
+	if weight < 0 {
+		return true
+	}
+	text := innerText(n)
+	if strings.Count(text, ",") >= 10 {
+		return false
+	}
+
+	paragraphs := countElements(n, atom.P)
+	images := countElements(n, atom.Img)
+	length := utf8.RuneCountInString(text)
+	density := linkDensity(n)
+
+	switch {
+	case images > 1 && float64(paragraphs)/float64(images) < 0.5:
+		return true
+	case length < 25 && images == 0:
+		return true
+	case weight < 25 && density > 0.2:
+		return true
+	case weight >= 25 && density > 0.5:
+		return true
+	}
+	return false
+}
+
+// innerText is the text inside n with whitespace collapsed.
+func innerText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			b.WriteByte(' ')
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// linkDensity is the share of n's text that sits inside links.
+func linkDensity(n *html.Node) float64 {
+	length := utf8.RuneCountInString(innerText(n))
+	if length == 0 {
+		return 0
+	}
+	linked := 0
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			linked += utf8.RuneCountInString(innerText(n))
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return float64(linked) / float64(length)
+}
+
+func hasBlockChildren(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && (divBlockElements[c.DataAtom] || hasBlockChildren(c)) {
+			return true
+		}
+	}
+	return false
+}
+
+func countElements(n *html.Node, a atom.Atom) int {
+	count := 0
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.DataAtom == a {
+			count++
+		}
+		count += countElements(c, a)
+	}
+	return count
+}
+
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == key {
+			return true
+		}
+	}
+	return false
+}
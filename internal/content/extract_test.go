@@ -0,0 +1,212 @@
+package content
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// paragraph is long enough, and has enough commas, to be scored.
+const paragraph = "The committee met on Tuesday, after weeks of delay, to settle the budget for the coming year, and agreed on most of it before lunch."
+
+func articlePage(body string) string {
+	return `<!DOCTYPE html><html><head><title>Page</title><script>var tracking = 1;</script></head><body>
+<header class="site-header"><a href="/">Home</a> <a href="/news">News</a></header>
+<nav><ul><li><a href="/a">Section A</a></li><li><a href="/b">Section B</a></li></ul></nav>
+` + body + `
+<div class="sidebar"><p>Subscribe to our newsletter, today, for more news, more views, and more offers than you could ever read.</p></div>
+<div id="comments"><p>First comment, which is long enough, to be scored, but sits in a comment thread and should go.</p></div>
+<footer><p>Copyright, all rights reserved, by a publisher with a long footer, that nobody reads.</p></footer>
+</body></html>`
+}
+
+func TestExtract(t *testing.T) {
+	page := articlePage(`
+<div class="article-body">
+  <h1>Budget agreed</h1>
+  <p>` + paragraph + `</p>
+  <p>` + paragraph + `</p>
+  <div class="share-tools"><a href="https://social.example/share">Share</a> <a href="https://mail.example/">Mail</a></div>
+  <p>` + paragraph + ` <a href="/minutes">Read the minutes</a>.</p>
+  <p style="display: none">Hidden paragraph, with commas, that a reader never sees on the page.</p>
+  <img src="/chart.png" alt="Chart">
+</div>`)
+
+	article, err := Extract(strings.NewReader(page), "text/html; charset=utf-8", "https://news.example/2024/budget")
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+
+	for _, want := range []string{
+		"The committee met on Tuesday",
+		`<a href="https://news.example/minutes" rel="nofollow noopener noreferrer">Read the minutes</a>`,
+		`<img src="https://news.example/chart.png" alt="Chart">`,
+	} {
+		if !strings.Contains(article.HTML, want) {
+			t.Errorf("article is missing %q:\n%s", want, article.HTML)
+		}
+	}
+	for _, unwanted := range []string{"Section A", "Subscribe", "First comment", "Copyright", "Share", "Hidden paragraph", "tracking"} {
+		if strings.Contains(article.HTML, unwanted) || strings.Contains(article.Text, unwanted) {
+			t.Errorf("article contains %q:\n%s", unwanted, article.HTML)
+		}
+	}
+	if article.WordCount != WordCount(article.Text) || article.WordCount < 3*20 {
+		t.Errorf("word count = %d for %q", article.WordCount, article.Text)
+	}
+}
+
+func TestExtractLeadSibling(t *testing.T) {
+	// The standfirst sits beside the container that scores highest and is
+	// kept because it reads like a sentence without links.
+	page := articlePage(`
+<main>
+  <p class="standfirst">A short lead sentence.</p>
+  <div class="entry">
+    <p>` + paragraph + `</p>
+    <p>` + paragraph + `</p>
+  </div>
+  <p class="related"><a href="/other">Other story</a></p>
+</main>`)
+
+	article, err := Extract(strings.NewReader(page), "", "https://news.example/story")
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if !strings.HasPrefix(article.Text, "A short lead sentence.") {
+		t.Errorf("text does not start with the lead: %q", article.Text)
+	}
+	if strings.Contains(article.Text, "Other story") {
+		t.Errorf("text contains the related link: %q", article.Text)
+	}
+}
+
+func TestExtractBaseHref(t *testing.T) {
+	page := `<html><head><base href="https://cdn.example/assets/"></head><body><article>
+<p>` + paragraph + ` <a href="notes.html">Notes</a></p><p>` + paragraph + `</p></article></body></html>`
+
+	article, err := Extract(strings.NewReader(page), "", "https://news.example/story")
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if !strings.Contains(article.HTML, `href="https://cdn.example/assets/notes.html"`) {
+		t.Errorf("link not resolved against <base>:\n%s", article.HTML)
+	}
+}
+
+func TestExtractCharset(t *testing.T) {
+	// "Café" in ISO-8859-1, with the charset only in the Content-Type.
+	page := "<html><body><article><p>Caf\xe9, " + paragraph + "</p><p>" + paragraph + "</p></article></body></html>"
+
+	article, err := Extract(strings.NewReader(page), "text/html; charset=iso-8859-1", "https://news.example/")
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if !strings.HasPrefix(article.Text, "Café, ") {
+		t.Errorf("text = %q", article.Text)
+	}
+}
+
+func TestExtractNoContent(t *testing.T) {
+	pages := map[string]string{
+		"empty":        "",
+		"links only":   articlePage(`<div><a href="/1">One</a> <a href="/2">Two</a></div>`),
+		"too short":    `<html><body><article><p>We use cookies, to improve, your experience, on this website.</p></article></body></html>`,
+		"consent wall": `<html><body><div class="cookie-banner gdpr"><p>` + paragraph + `</p><p>` + paragraph + `</p></div></body></html>`,
+	}
+	for name, page := range pages {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Extract(strings.NewReader(page), "", "https://news.example/"); !errors.Is(err, ErrNoContent) {
+				t.Errorf("Extract = %v, want ErrNoContent", err)
+			}
+		})
+	}
+}
+
+func parseElement(t *testing.T, fragment string) *html.Node {
+	t.Helper()
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), fragmentContext)
+	if err != nil || len(nodes) == 0 {
+		t.Fatalf("parse %q: %v", fragment, err)
+	}
+	return nodes[0]
+}
+
+func TestClassWeight(t *testing.T) {
+	tests := []struct {
+		fragment string
+		want     float64
+	}{
+		{`<div></div>`, 0},
+		{`<div class="article-body"></div>`, 25},
+		{`<div class="sidebar"></div>`, -25},
+		{`<div class="post" id="entry"></div>`, 50},
+		{`<div class="post" id="comments"></div>`, 0},
+		{`<div class="post-footer"></div>`, 0},
+	}
+	for _, tt := range tests {
+		if got := classWeight(parseElement(t, tt.fragment)); got != tt.want {
+			t.Errorf("classWeight(%s) = %v, want %v", tt.fragment, got, tt.want)
+		}
+	}
+}
+
+func TestInitialScore(t *testing.T) {
+	tests := []struct {
+		fragment string
+		want     float64
+	}{
+		{`<div></div>`, 5},
+		{`<article class="content"></article>`, 30},
+		{`<blockquote></blockquote>`, 3},
+		{`<ul></ul>`, -3},
+		{`<h2></h2>`, -5},
+		{`<span></span>`, 0},
+	}
+	for _, tt := range tests {
+		if got := initialScore(parseElement(t, tt.fragment)); got != tt.want {
+			t.Errorf("initialScore(%s) = %v, want %v", tt.fragment, got, tt.want)
+		}
+	}
+}
+
+func TestLinkDensity(t *testing.T) {
+	tests := []struct {
+		fragment string
+		want     float64
+	}{
+		{`<p></p>`, 0},
+		{`<p>no links here</p>`, 0},
+		{`<p><a href="/">all linked</a></p>`, 1},
+		{`<p>half <a href="/">link</a></p>`, 4.0 / 9},
+	}
+	for _, tt := range tests {
+		if got := linkDensity(parseElement(t, tt.fragment)); got != tt.want {
+			t.Errorf("linkDensity(%s) = %v, want %v", tt.fragment, got, tt.want)
+		}
+	}
+}
+
+func TestIsBoilerplate(t *testing.T) {
+	tests := []struct {
+		fragment string
+		want     bool
+	}{
+		{`<nav></nav>`, true},
+		{`<div role="navigation"></div>`, true},
+		{`<div hidden></div>`, true},
+		{`<div aria-hidden="true"></div>`, true},
+		{`<div style="visibility: hidden"></div>`, true},
+		{`<div class="comment-list"></div>`, true},
+		{`<div class="comment-list main-content"></div>`, false},
+		{`<article class="sidebar"></article>`, false},
+		{`<div class="story"></div>`, false},
+	}
+	for _, tt := range tests {
+		if got := isBoilerplate(parseElement(t, tt.fragment)); got != tt.want {
+			t.Errorf("isBoilerplate(%s) = %v, want %v", tt.fragment, got, tt.want)
+		}
+	}
+}
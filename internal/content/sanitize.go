@@ -0,0 +1,289 @@
+package content
+
+import (
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// allowedElements lists the elements Sanitize keeps and the attributes each
+// may carry. Any other element is unwrapped: its content is kept without it.
+var allowedElements = map[atom.Atom][]string{
+	atom.A:          {"href", "title"},
+	atom.Abbr:       {"title"},
+	atom.B:          nil,
+	atom.Blockquote: {"cite"},
+	atom.Br:         nil,
+	atom.Caption:    nil,
+	atom.Cite:       nil,
+	atom.Code:       nil,
+	atom.Dd:         nil,
+	atom.Del:        nil,
+	atom.Dl:         nil,
+	atom.Dt:         nil,
+	atom.Em:         nil,
+	atom.Figcaption: nil,
+	atom.Figure:     nil,
+	atom.H1:         nil,
+	atom.H2:         nil,
+	atom.H3:         nil,
+	atom.H4:         nil,
+	atom.H5:         nil,
+	atom.H6:         nil,
+	atom.Hr:         nil,
+	atom.I:          nil,
+	atom.Img:        {"src", "alt", "title", "width", "height"},
+	atom.Ins:        nil,
+	atom.Kbd:        nil,
+	atom.Li:         nil,
+	atom.Mark:       nil,
+	atom.Ol:         {"start"},
+	atom.P:          nil,
+	atom.Pre:        nil,
+	atom.Q:          {"cite"},
+	atom.S:          nil,
+	atom.Small:      nil,
+	atom.Strong:     nil,
+	atom.Sub:        nil,
+	atom.Sup:        nil,
+	atom.Table:      nil,
+	atom.Tbody:      nil,
+	atom.Td:         {"colspan", "rowspan"},
+	atom.Tfoot:      nil,
+	atom.Th:         {"colspan", "rowspan", "scope"},
+	atom.Thead:      nil,
+	atom.Time:       {"datetime"},
+	atom.Tr:         nil,
+	atom.U:          nil,
+	atom.Ul:         nil,
+}
+
+// droppedElements are removed together with everything inside them.
+var droppedElements = map[atom.Atom]bool{
+	atom.Applet:   true,
+	atom.Audio:    true,
+	atom.Base:     true,
+	atom.Button:   true,
+	atom.Canvas:   true,
+	atom.Embed:    true,
+	atom.Form:     true,
+	atom.Frame:    true,
+	atom.Frameset: true,
+	atom.Head:     true,
+	atom.Iframe:   true,
+	atom.Input:    true,
+	atom.Link:     true,
+	atom.Math:     true,
+	atom.Meta:     true,
+	atom.Noscript: true,
+	atom.Object:   true,
+	atom.Script:   true,
+	atom.Select:   true,
+	atom.Style:    true,
+	atom.Svg:      true,
+	atom.Template: true,
+	atom.Textarea: true,
+	atom.Title:    true,
+	atom.Video:    true,
+}
+
+// urlAttributes are resolved against the base URL and must use an allowed
+// scheme.
+var urlAttributes = map[string]bool{"href": true, "src": true, "cite": true}
+
+// trackerHosts serve images whose only purpose is counting readers.
+var trackerHosts = map[string]bool{
+	"feeds.feedburner.com":     true,
+	"feeds.wordpress.com":      true,
+	"pixel.wp.com":             true,
+	"stats.wordpress.com":      true,
+	"pixel.quantserve.com":     true,
+	"www.google-analytics.com": true,
+	"ad.doubleclick.net":       true,
+}
+
+// fragmentContext parses fragments as the content of a <div>.
+var fragmentContext = &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+
+// Sanitize reduces an HTML fragment to the elements and attributes in
+// allowedElements. Relative links and image sources are resolved against
+// baseURL, URLs with any scheme other than http, https or, for links,
+// mailto are dropped, and so are tracking pixels.
+func Sanitize(fragment, baseURL string) string {
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), fragmentContext)
+	if err != nil {
+		return ""
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil || !base.IsAbs() {
+		base = nil
+	}
+
+	var b strings.Builder
+	for _, n := range nodes {
+		writeSanitized(&b, n, base)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func writeSanitized(b *strings.Builder, n *html.Node, base *url.URL) {
+	switch n.Type {
+	case html.TextNode:
+		b.WriteString(html.EscapeString(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	if droppedElements[n.DataAtom] {
+		return
+	}
+	allowed, ok := allowedElements[n.DataAtom]
+	if !ok {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			writeSanitized(b, c, base)
+		}
+		return
+	}
+
+	attrs := sanitizeAttributes(n, allowed, base)
+	if n.DataAtom == atom.Img && (attrs["src"] == "" || isTrackingPixel(n, attrs["src"])) {
+		return
+	}
+
+	b.WriteString("<")
+	b.WriteString(n.Data)
+	// Attributes are written in allowlist order so the output is stable.
+	for _, key := range allowed {
+		if value, ok := attrs[key]; ok {
+			b.WriteString(" " + key + `="` + html.EscapeString(value) + `"`)
+		}
+	}
+	if n.DataAtom == atom.A && attrs["href"] != "" {
+		b.WriteString(` rel="nofollow noopener noreferrer"`)
+	}
+	b.WriteString(">")
+
+	if n.DataAtom == atom.Br || n.DataAtom == atom.Hr || n.DataAtom == atom.Img {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		writeSanitized(b, c, base)
+	}
+	b.WriteString("</" + n.Data + ">")
+}
+
+// sanitizeAttributes returns the allowed attributes of n. Lazy-loaded
+// images keep their real source, which is commonly in data-src while src
+// holds a placeholder.
+func sanitizeAttributes(n *html.Node, allowed []string, base *url.URL) map[string]string {
+	attrs := make(map[string]string, len(allowed))
+	lazySrc := ""
+	for _, attr := range n.Attr {
+		if attr.Namespace != "" {
+			continue
+		}
+		key := strings.ToLower(attr.Key)
+		if key == "data-src" && n.DataAtom == atom.Img {
+			lazySrc = attr.Val
+			continue
+		}
+		if !slices.Contains(allowed, key) {
+			continue
+		}
+
+		value := attr.Val
+		if urlAttributes[key] {
+			resolved, ok := resolveURL(base, value, key == "href")
+			if !ok {
+				continue
+			}
+			value = resolved
+		}
+		attrs[key] = value
+	}
+
+	if lazySrc != "" {
+		// A data: placeholder has already been dropped from src.
+		if resolved, ok := resolveURL(base, lazySrc, false); ok && attrs["src"] == "" {
+			attrs["src"] = resolved
+		}
+	}
+	return attrs
+}
+
+// isTrackingPixel reports an image that is 1x1 or smaller, or served by a
+// known tracker.
+func isTrackingPixel(n *html.Node, src string) bool {
+	if u, err := url.Parse(src); err == nil && trackerHosts[strings.ToLower(u.Hostname())] {
+		return true
+	}
+
+	width, widthOK := pixels(attr(n, "width"))
+	height, heightOK := pixels(attr(n, "height"))
+	if widthOK && heightOK && width <= 1 && height <= 1 {
+		return true
+	}
+
+	// Inline styles can shrink an image just the same.
+	width, height = 2, 2
+	for _, declaration := range strings.Split(attr(n, "style"), ";") {
+		property, value, _ := strings.Cut(declaration, ":")
+		switch strings.ToLower(strings.TrimSpace(property)) {
+		case "width":
+			if v, ok := pixels(value); ok {
+				width = v
+			}
+		case "height":
+			if v, ok := pixels(value); ok {
+				height = v
+			}
+		}
+	}
+	return width <= 1 && height <= 1
+}
+
+// pixels parses an HTML length such as "1" or "1px".
+func pixels(value string) (int, bool) {
+	value = strings.TrimSuffix(strings.TrimSpace(strings.ToLower(value)), "px")
+	n, err := strconv.Atoi(value)
+	return n, err == nil
+}
+
+// resolveURL makes raw absolute against base and reports whether the
+// result is safe to link to.
+func resolveURL(base *url.URL, raw string, link bool) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", false
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+	case "mailto":
+		if !link {
+			return "", false
+		}
+	case "":
+		// Without a base a relative URL is kept as it is; it cannot point
+		// anywhere but the site that serves it.
+		if base != nil {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+	return u.String(), true
+}
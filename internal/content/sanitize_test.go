@@ -0,0 +1,146 @@
+package content
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	const base = "https://example.com/blog/post"
+	const rel = ` rel="nofollow noopener noreferrer"`
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		// Scripting.
+		{"script element", `<p>Hi<script>alert(1)</script></p>`, `<p>Hi</p>`},
+		{"style element", `<style>p{color:red}</style><p>Hi</p>`, `<p>Hi</p>`},
+		{"iframe", `<iframe src="https://evil.example/"></iframe><p>Hi</p>`, `<p>Hi</p>`},
+		{"form controls", `<form action="/x"><input name="q"><button>Go</button></form>ok`, `ok`},
+		{"svg", `<svg onload="alert(1)"><a href="https://example.com/">x</a></svg>ok`, `ok`},
+		{"event handlers", `<p onclick="alert(1)" onmouseover="alert(2)">Hi</p>`, `<p>Hi</p>`},
+		{"uppercase event handler", `<img src="a.png" ONERROR="alert(1)">`, `<img src="https://example.com/blog/a.png">`},
+		{"style attribute", `<p style="background:url(javascript:alert(1))">Hi</p>`, `<p>Hi</p>`},
+		{"class and id", `<p class="x" id="y">Hi</p>`, `<p>Hi</p>`},
+		{"comment", `<p>Hi<!-- <script>alert(1)</script> --></p>`, `<p>Hi</p>`},
+
+		// URL schemes.
+		{"javascript link", `<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"mixed case javascript link", `<a href="JaVaScRiPt:alert(1)">x</a>`, `<a>x</a>`},
+		{"padded javascript link", `<a href="  javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"entity encoded javascript link", `<a href="javascript&colon;alert(1)">x</a>`, `<a>x</a>`},
+		{"tab in javascript link", "<a href=\"java\tscript:alert(1)\">x</a>", `<a>x</a>`},
+		{"vbscript link", `<a href="vbscript:msgbox(1)">x</a>`, `<a>x</a>`},
+		{"data link", `<a href="data:text/html,<script>alert(1)</script>">x</a>`, `<a>x</a>`},
+		{"data image", `<img src="data:image/png;base64,AAAA">`, ``},
+		{"javascript image", `<img src="javascript:alert(1)">`, ``},
+		{"javascript cite", `<blockquote cite="javascript:alert(1)">q</blockquote>`, `<blockquote>q</blockquote>`},
+		{"mailto link", `<a href="mailto:me@example.com">mail</a>`, `<a href="mailto:me@example.com"` + rel + `>mail</a>`},
+		{"mailto image", `<img src="mailto:me@example.com">`, ``},
+
+		// Relative URLs.
+		{"relative link", `<a href="../about">x</a>`, `<a href="https://example.com/about"` + rel + `>x</a>`},
+		{"root relative image", `<img src="/img/a.png" alt="A">`, `<img src="https://example.com/img/a.png" alt="A">`},
+		{"protocol relative link", `<a href="//cdn.example.net/x">x</a>`, `<a href="https://cdn.example.net/x"` + rel + `>x</a>`},
+		{"lazy image", `<img src="data:image/gif;base64,R0lGOD" data-src="/real.jpg">`, `<img src="https://example.com/real.jpg">`},
+
+		// Tracking pixels.
+		{"one pixel image", `<p>Hi<img src="/t.gif" width="1" height="1"></p>`, `<p>Hi</p>`},
+		{"one pixel image with units", `<img src="/t.gif" width="1px" height="0px">`, ``},
+		{"styled pixel", `<img src="/t.gif" style="width: 1px; height: 1px">`, ``},
+		{"tracker host", `<img src="https://stats.wordpress.com/b.gif?x=1" width="50" height="50">`, ``},
+		{"image without source", `<img alt="nothing">`, ``},
+		{"ordinary image", `<img src="/a.png" width="640" height="480">`, `<img src="https://example.com/a.png" width="640" height="480">`},
+
+		// Structure.
+		{"unknown elements unwrapped", `<div><span>Hello <custom>world</custom></span></div>`, `Hello world`},
+		{"allowed attributes in order", `<a title="T" href="/x" target="_blank">x</a>`, `<a href="https://example.com/x" title="T"` + rel + `>x</a>`},
+		{"attribute values escaped", `<a href="/x" title='"><script>alert(1)</script>'>x</a>`, `<a href="https://example.com/x" title="&#34;&gt;&lt;script&gt;alert(1)&lt;/script&gt;"` + rel + `>x</a>`},
+		{"text escaped", `<p>1 &lt; 2 &amp; &lt;b&gt;</p>`, `<p>1 &lt; 2 &amp; &lt;b&gt;</p>`},
+		{"void elements", `line<br>break<hr>`, `line<br>break<hr>`},
+		{"table", `<table><tr><td colspan="2" onclick="x">a</td></tr></table>`, `<table><tbody><tr><td colspan="2">a</td></tr></tbody></table>`},
+		{"unclosed tags", `<p><b>bold`, `<p><b>bold</b></p>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sanitize(tt.in, base); got != tt.want {
+				t.Errorf("Sanitize(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeWithoutBase(t *testing.T) {
+	tests := []struct {
+		name string
+		base string
+		in   string
+		want string
+	}{
+		{"relative link kept", "", `<a href="/about">x</a>`, `<a href="/about" rel="nofollow noopener noreferrer">x</a>`},
+		{"relative base ignored", "/blog/", `<img src="a.png">`, `<img src="a.png">`},
+		{"javascript still dropped", "", `<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sanitize(tt.in, tt.base); got != tt.want {
+				t.Errorf("Sanitize(%q, %q) = %q, want %q", tt.in, tt.base, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeNeverEmitsScript(t *testing.T) {
+	inputs := []string{
+		`<scr<script>ipt>alert(1)</script>`,
+		`<<script>script>alert(1)<</script>/script>`,
+		`<noscript><p title="</noscript><img src=x onerror=alert(1)>"></noscript>`,
+		`<math><mtext><table><mglyph><style><img src=x onerror=alert(1)>`,
+		`<xmp><script>alert(1)</script></xmp>`,
+		`<textarea><script>alert(1)</script></textarea>`,
+		`<img src="x" onerror="alert(1)"//`,
+	}
+	for _, in := range inputs {
+		got := strings.ToLower(Sanitize(in, "https://example.com/"))
+		if strings.Contains(got, "<script") || strings.Contains(got, "onerror") || strings.Contains(got, "<style") {
+			t.Errorf("Sanitize(%q) = %q", in, got)
+		}
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`<p>One</p><p>Two</p>`, "One\n\nTwo"},
+		{`line<br>break`, "line\nbreak"},
+		{`<p>  spaced   out  </p>`, "spaced out"},
+		{`<p>Tom &amp; Jerry</p><script>alert(1)</script>`, "Tom & Jerry"},
+		{`<ul><li>a</li><li>b</li></ul>`, "a\n\nb"},
+	}
+	for _, tt := range tests {
+		if got := PlainText(tt.in); got != tt.want {
+			t.Errorf("PlainText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestReadingTime(t *testing.T) {
+	tests := []struct {
+		words int
+		want  int
+	}{
+		{0, 0},
+		{1, 1},
+		{wordsPerMinute, 1},
+		{wordsPerMinute + 1, 2},
+	}
+	for _, tt := range tests {
+		if got := ReadingTime(tt.words); got != tt.want {
+			t.Errorf("ReadingTime(%d) = %d, want %d", tt.words, got, tt.want)
+		}
+	}
+}
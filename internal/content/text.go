@@ -0,0 +1,149 @@
+package content
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// wordsPerMinute is the reading speed ReadingTime assumes.
+const wordsPerMinute = 230
+
+// blockElements start a new paragraph in plain text.
+var blockElements = map[atom.Atom]bool{
+	atom.Address:    true,
+	atom.Article:    true,
+	atom.Aside:      true,
+	atom.Blockquote: true,
+	atom.Caption:    true,
+	atom.Dd:         true,
+	atom.Div:        true,
+	atom.Dl:         true,
+	atom.Dt:         true,
+	atom.Figcaption: true,
+	atom.Figure:     true,
+	atom.Footer:     true,
+	atom.H1:         true,
+	atom.H2:         true,
+	atom.H3:         true,
+	atom.H4:         true,
+	atom.H5:         true,
+	atom.H6:         true,
+	atom.Header:     true,
+	atom.Hr:         true,
+	atom.Li:         true,
+	atom.Main:       true,
+	atom.Ol:         true,
+	atom.P:          true,
+	atom.Pre:        true,
+	atom.Section:    true,
+	atom.Table:      true,
+	atom.Tr:         true,
+	atom.Ul:         true,
+}
+
+// PlainText renders an HTML fragment as text, with blocks separated by a
+// blank line and <br> as a line break.
+func PlainText(fragment string) string {
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), fragmentContext)
+	if err != nil {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, n := range nodes {
+		writeText(&b, n, false)
+	}
+	return tidyText(b.String())
+}
+
+// WordCount counts the whitespace separated words in text.
+func WordCount(text string) int {
+	return len(strings.Fields(text))
+}
+
+// ReadingTime estimates the minutes needed to read words words, rounded
+// up so that any text takes at least a minute.
+func ReadingTime(words int) int {
+	if words <= 0 {
+		return 0
+	}
+	return (words + wordsPerMinute - 1) / wordsPerMinute
+}
+
+func writeText(b *strings.Builder, n *html.Node, pre bool) {
+	switch n.Type {
+	case html.TextNode:
+		if pre {
+			b.WriteString(n.Data)
+		} else {
+			b.WriteString(collapseSpace(n.Data))
+		}
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	if droppedElements[n.DataAtom] {
+		return
+	}
+	if n.DataAtom == atom.Br {
+		b.WriteString("\n")
+		return
+	}
+
+	block := blockElements[n.DataAtom]
+	if block {
+		b.WriteString("\n\n")
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		writeText(b, c, pre || n.DataAtom == atom.Pre)
+	}
+	if block {
+		b.WriteString("\n\n")
+	}
+}
+
+// collapseSpace replaces each run of whitespace, including newlines, with
+// a single space. Leading and trailing space is kept so that words in
+// neighbouring inline elements stay apart.
+func collapseSpace(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	if space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+// tidyText trims every line and drops empty ones, keeping a single blank
+// line between paragraphs.
+func tidyText(s string) string {
+	var paragraphs []string
+	for _, paragraph := range strings.Split(s, "\n\n") {
+		var lines []string
+		for _, line := range strings.Split(paragraph, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) > 0 {
+			paragraphs = append(paragraphs, strings.Join(lines, "\n"))
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
@@ -6,28 +6,121 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type Folder struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Name      string
+	ParentID  uuid.NullUUID
+}
+
 type Post struct {
-	ID          uuid.UUID
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Title           string
+	Description     sql.NullString
+	Url             string
+	PublishedAt     sql.NullTime
+	Postname        sql.NullString
+	WebpageID       uuid.NullUUID
+	Starred         bool
+	DescriptionText sql.NullString
+	Author          sql.NullString
+	Categories      json.RawMessage
+	Enclosures      json.RawMessage
+	Read            bool
+	Score           float64
+	ClusterID       uuid.NullUUID
+	Simhash         sql.NullInt64
+	UrlKey          sql.NullString
+	TaggedAt        sql.NullTime
+}
+
+type PostContent struct {
+	PostID      uuid.UUID
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
-	Title       string
-	Description sql.NullString
-	Url         string
-	PublishedAt sql.NullTime
-	Postname    sql.NullString
+	Status      string
+	Content     sql.NullString
+	ContentText sql.NullString
+	WordCount   int32
+	Attempts    int32
+	LastError   sql.NullString
+	RetryAt     sql.NullTime
+	FetchedAt   sql.NullTime
+}
+
+type PostTag struct {
+	PostID    uuid.UUID
+	Tag       string
+	Source    string
+	Score     float64
+	CreatedAt time.Time
+}
+
+type PostTerm struct {
+	PostID uuid.UUID
+	Terms  json.RawMessage
+}
+
+type PostsArchive struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Title           string
+	Description     sql.NullString
+	Url             string
+	PublishedAt     sql.NullTime
+	Postname        sql.NullString
+	WebpageID       uuid.NullUUID
+	ArchivedAt      time.Time
+	DescriptionText sql.NullString
+	Author          sql.NullString
+	Categories      json.RawMessage
+	Enclosures      json.RawMessage
+}
+
+type TermDocument struct {
+	Term      string
+	Documents int32
 }
 
 type Webpage struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	Type          string
-	LastUpdatedAt sql.NullTime
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Name            string
+	Url             string
+	Type            string
+	LastUpdatedAt   sql.NullTime
+	LeaseOwner      sql.NullString
+	LeaseExpiresAt  sql.NullTime
+	LastFetchStatus sql.NullString
+	LastFetchError  sql.NullString
+	MaxItemAgeDays  sql.NullInt32
+	UserAgent       sql.NullString
+	BackoffUntil    sql.NullTime
+	RedirectUrl     sql.NullString
+	RedirectCount   int32
+	FullText        bool
+	SiteUrl         sql.NullString
+}
+
+type WebpageFolder struct {
+	WebpageID uuid.UUID
+	FolderID  uuid.UUID
+	CreatedAt time.Time
+}
+
+type WebpageUrlAlias struct {
+	Url       string
+	WebpageID uuid.UUID
+	CreatedAt time.Time
 }
@@ -11,12 +11,65 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const archiveExpiredPosts = `-- name: ArchiveExpiredPosts :execrows
+WITH expired AS (
+    DELETE FROM posts
+    WHERE id IN (
+        SELECT id FROM posts
+        WHERE COALESCE(published_at, created_at) < $1::timestamp
+        AND NOT starred
+        LIMIT $2
+    )
+    RETURNING id, created_at, updated_at, title, description, url, published_at, postName, webpage_id, description_text, author, categories, enclosures
+),
+dropped AS (
+    SELECT term, COUNT(*)::integer AS documents
+    FROM post_terms, jsonb_array_elements_text(post_terms.terms) AS term
+    WHERE post_terms.post_id IN (SELECT id FROM expired)
+    GROUP BY term
+),
+locked AS (
+    SELECT term_documents.term FROM term_documents
+    WHERE term_documents.term IN (SELECT term FROM dropped)
+    ORDER BY term_documents.term
+    FOR UPDATE
+),
+uncounted AS (
+    UPDATE term_documents
+    SET documents = term_documents.documents - dropped.documents
+    FROM dropped
+    WHERE term_documents.term = dropped.term
+    AND term_documents.term IN (SELECT term FROM locked)
+)
+INSERT INTO posts_archive (id, created_at, updated_at, title, description, url, published_at, postName, webpage_id, description_text, author, categories, enclosures, archived_at)
+SELECT id, created_at, updated_at, title, description, url, published_at, postName, webpage_id, description_text, author, categories, enclosures, $3::timestamp
+FROM expired
+ON CONFLICT (id) DO NOTHING
+`
+
+type ArchiveExpiredPostsParams struct {
+	Cutoff     time.Time
+	BatchSize  int32
+	ArchivedAt time.Time
+}
+
+// Archived posts leave the corpus like deleted ones; see
+// DeleteExpiredPosts.
+func (q *Queries) ArchiveExpiredPosts(ctx context.Context, arg ArchiveExpiredPostsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, archiveExpiredPosts, arg.Cutoff, arg.BatchSize, arg.ArchivedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const createPost = `-- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, description, url, published_at, postName)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, created_at, updated_at, title, description, url, published_at, postname
+INSERT INTO posts (id, created_at, updated_at, title, description, url, published_at, postName, webpage_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, created_at, updated_at, title, description, url, published_at, postname, webpage_id, starred, description_text, author, categories, enclosures, read, score, cluster_id, simhash, url_key, tagged_at
 `
 
 type CreatePostParams struct {
@@ -28,6 +81,7 @@ type CreatePostParams struct {
 	Url         string
 	PublishedAt sql.NullTime
 	Postname    sql.NullString
+	WebpageID   uuid.NullUUID
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -40,6 +94,7 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Url,
 		arg.PublishedAt,
 		arg.Postname,
+		arg.WebpageID,
 	)
 	var i Post
 	err := row.Scan(
@@ -51,12 +106,231 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Url,
 		&i.PublishedAt,
 		&i.Postname,
+		&i.WebpageID,
+		&i.Starred,
+		&i.DescriptionText,
+		&i.Author,
+		&i.Categories,
+		&i.Enclosures,
+		&i.Read,
+		&i.Score,
+		&i.ClusterID,
+		&i.Simhash,
+		&i.UrlKey,
+		&i.TaggedAt,
+	)
+	return i, err
+}
+
+const deleteExpiredPosts = `-- name: DeleteExpiredPosts :execrows
+WITH expired AS (
+    SELECT id FROM posts
+    WHERE COALESCE(published_at, created_at) < $1::timestamp
+    AND NOT starred
+    LIMIT $2
+    FOR UPDATE
+),
+dropped AS (
+    SELECT term, COUNT(*)::integer AS documents
+    FROM post_terms, jsonb_array_elements_text(post_terms.terms) AS term
+    WHERE post_terms.post_id IN (SELECT id FROM expired)
+    GROUP BY term
+),
+locked AS (
+    SELECT term_documents.term FROM term_documents
+    WHERE term_documents.term IN (SELECT term FROM dropped)
+    ORDER BY term_documents.term
+    FOR UPDATE
+),
+uncounted AS (
+    UPDATE term_documents
+    SET documents = term_documents.documents - dropped.documents
+    FROM dropped
+    WHERE term_documents.term = dropped.term
+    AND term_documents.term IN (SELECT term FROM locked)
+)
+DELETE FROM posts
+WHERE id IN (SELECT id FROM expired)
+`
+
+type DeleteExpiredPostsParams struct {
+	Cutoff    time.Time
+	BatchSize int32
+}
+
+// Removed posts leave the corpus keywords are weighed against, so their
+// terms are taken off the document frequencies. Those rows are locked in
+// term order, as the tagger locks them, so the two cannot deadlock.
+func (q *Queries) DeleteExpiredPosts(ctx context.Context, arg DeleteExpiredPostsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteExpiredPosts, arg.Cutoff, arg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getFeedPosts = `-- name: GetFeedPosts :many
+SELECT id, created_at, updated_at, title, description, url, published_at, postname, webpage_id, starred, description_text, author, categories, enclosures, read, score, cluster_id, simhash, url_key, tagged_at FROM posts
+ORDER BY COALESCE(published_at, created_at) DESC
+LIMIT $1
+`
+
+func (q *Queries) GetFeedPosts(ctx context.Context, limit int32) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedPosts, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Description,
+			&i.Url,
+			&i.PublishedAt,
+			&i.Postname,
+			&i.WebpageID,
+			&i.Starred,
+			&i.DescriptionText,
+			&i.Author,
+			&i.Categories,
+			&i.Enclosures,
+			&i.Read,
+			&i.Score,
+			&i.ClusterID,
+			&i.Simhash,
+			&i.UrlKey,
+			&i.TaggedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedPostsByWebpage = `-- name: GetFeedPostsByWebpage :many
+SELECT id, created_at, updated_at, title, description, url, published_at, postname, webpage_id, starred, description_text, author, categories, enclosures, read, score, cluster_id, simhash, url_key, tagged_at FROM posts
+WHERE webpage_id = $1
+ORDER BY COALESCE(published_at, created_at) DESC
+LIMIT $2
+`
+
+type GetFeedPostsByWebpageParams struct {
+	WebpageID uuid.NullUUID
+	Limit     int32
+}
+
+func (q *Queries) GetFeedPostsByWebpage(ctx context.Context, arg GetFeedPostsByWebpageParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedPostsByWebpage, arg.WebpageID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Description,
+			&i.Url,
+			&i.PublishedAt,
+			&i.Postname,
+			&i.WebpageID,
+			&i.Starred,
+			&i.DescriptionText,
+			&i.Author,
+			&i.Categories,
+			&i.Enclosures,
+			&i.Read,
+			&i.Score,
+			&i.ClusterID,
+			&i.Simhash,
+			&i.UrlKey,
+			&i.TaggedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getNextPostID = `-- name: GetNextPostID :one
+SELECT id FROM posts
+WHERE webpage_id = $1
+AND (COALESCE(published_at, created_at), id) > ($2::timestamp, $3::uuid)
+ORDER BY COALESCE(published_at, created_at) ASC, id ASC
+LIMIT 1
+`
+
+type GetNextPostIDParams struct {
+	WebpageID uuid.NullUUID
+	SortAt    time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) GetNextPostID(ctx context.Context, arg GetNextPostIDParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getNextPostID, arg.WebpageID, arg.SortAt, arg.ID)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getPostByID = `-- name: GetPostByID :one
+SELECT id, created_at, updated_at, title, description, url, published_at, postname, webpage_id, starred, description_text, author, categories, enclosures, read, score, cluster_id, simhash, url_key, tagged_at FROM posts
+WHERE id = $1
+`
+
+func (q *Queries) GetPostByID(ctx context.Context, id uuid.UUID) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByID, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Description,
+		&i.Url,
+		&i.PublishedAt,
+		&i.Postname,
+		&i.WebpageID,
+		&i.Starred,
+		&i.DescriptionText,
+		&i.Author,
+		&i.Categories,
+		&i.Enclosures,
+		&i.Read,
+		&i.Score,
+		&i.ClusterID,
+		&i.Simhash,
+		&i.UrlKey,
+		&i.TaggedAt,
 	)
 	return i, err
 }
 
 const getPosts = `-- name: GetPosts :many
-SELECT id, created_at, updated_at, title, description, url, published_at, postname 
+SELECT id, created_at, updated_at, title, description, url, published_at, postname, webpage_id, starred, description_text, author, categories, enclosures, read, score, cluster_id, simhash, url_key, tagged_at
 FROM posts 
 ORDER BY created_at DESC 
 LIMIT 30
@@ -80,6 +354,18 @@ func (q *Queries) GetPosts(ctx context.Context) ([]Post, error) {
 			&i.Url,
 			&i.PublishedAt,
 			&i.Postname,
+			&i.WebpageID,
+			&i.Starred,
+			&i.DescriptionText,
+			&i.Author,
+			&i.Categories,
+			&i.Enclosures,
+			&i.Read,
+			&i.Score,
+			&i.ClusterID,
+			&i.Simhash,
+			&i.UrlKey,
+			&i.TaggedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -93,3 +379,407 @@ func (q *Queries) GetPosts(ctx context.Context) ([]Post, error) {
 	}
 	return items, nil
 }
+
+const getPostsInFolder = `-- name: GetPostsInFolder :many
+WITH RECURSIVE subtree AS (
+    SELECT folders.id FROM folders WHERE folders.id = $1::uuid
+    UNION
+    SELECT folders.id FROM folders JOIN subtree ON folders.parent_id = subtree.id
+)
+SELECT id, created_at, updated_at, title, description, url, published_at, postname, webpage_id, starred, description_text, author, categories, enclosures, read, score, cluster_id, simhash, url_key, tagged_at
+FROM posts
+WHERE webpage_id IN (
+    SELECT webpage_id FROM webpage_folders
+    WHERE folder_id IN (SELECT id FROM subtree)
+)
+ORDER BY created_at DESC
+LIMIT 30
+`
+
+func (q *Queries) GetPostsInFolder(ctx context.Context, folderID uuid.UUID) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsInFolder, folderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Description,
+			&i.Url,
+			&i.PublishedAt,
+			&i.Postname,
+			&i.WebpageID,
+			&i.Starred,
+			&i.DescriptionText,
+			&i.Author,
+			&i.Categories,
+			&i.Enclosures,
+			&i.Read,
+			&i.Score,
+			&i.ClusterID,
+			&i.Simhash,
+			&i.UrlKey,
+			&i.TaggedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPreviousPostID = `-- name: GetPreviousPostID :one
+SELECT id FROM posts
+WHERE webpage_id = $1
+AND (COALESCE(published_at, created_at), id) < ($2::timestamp, $3::uuid)
+ORDER BY COALESCE(published_at, created_at) DESC, id DESC
+LIMIT 1
+`
+
+type GetPreviousPostIDParams struct {
+	WebpageID uuid.NullUUID
+	SortAt    time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) GetPreviousPostID(ctx context.Context, arg GetPreviousPostIDParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getPreviousPostID, arg.WebpageID, arg.SortAt, arg.ID)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getTrendingPosts = `-- name: GetTrendingPosts :many
+WITH RECURSIVE subtree AS (
+    SELECT folders.id FROM folders WHERE folders.id = $1::uuid
+    UNION
+    SELECT folders.id FROM folders JOIN subtree ON folders.parent_id = subtree.id
+)
+SELECT id, created_at, updated_at, title, description, url, published_at, postname, webpage_id, starred, description_text, author, categories, enclosures, read, score, cluster_id, simhash, url_key, tagged_at
+FROM posts
+WHERE score > 0
+AND ($1::uuid IS NULL OR webpage_id IN (
+    SELECT webpage_id FROM webpage_folders
+    WHERE folder_id IN (SELECT id FROM subtree)
+))
+AND ($2::text IS NULL OR id IN (SELECT post_id FROM post_tags WHERE tag = $2::text))
+ORDER BY score DESC, id ASC
+LIMIT 30
+`
+
+type GetTrendingPostsParams struct {
+	FolderID uuid.NullUUID
+	Tag      sql.NullString
+}
+
+func (q *Queries) GetTrendingPosts(ctx context.Context, arg GetTrendingPostsParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getTrendingPosts, arg.FolderID, arg.Tag)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Description,
+			&i.Url,
+			&i.PublishedAt,
+			&i.Postname,
+			&i.WebpageID,
+			&i.Starred,
+			&i.DescriptionText,
+			&i.Author,
+			&i.Categories,
+			&i.Enclosures,
+			&i.Read,
+			&i.Score,
+			&i.ClusterID,
+			&i.Simhash,
+			&i.UrlKey,
+			&i.TaggedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const resetStalePostScores = `-- name: ResetStalePostScores :execrows
+UPDATE posts
+SET score = 0
+WHERE score <> 0
+AND COALESCE(published_at, created_at) < $1::timestamp
+`
+
+func (q *Queries) ResetStalePostScores(ctx context.Context, windowStart time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, resetStalePostScores, windowStart)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const scoreRecentPosts = `-- name: ScoreRecentPosts :execrows
+WITH recent AS (
+    SELECT
+        id,
+        webpage_id,
+        GREATEST(EXTRACT(EPOCH FROM ($1::timestamp - COALESCE(published_at, created_at))) / 3600, 0)::float8 AS age_hours,
+        regexp_replace(lower(url), '^https?://(www\.)?|[?#].*$|/+$', '', 'g') AS url_key,
+        btrim(regexp_replace(lower(title), '[^[:alnum:]]+', ' ', 'g')) AS title_key
+    FROM posts
+    WHERE COALESCE(published_at, created_at) >= $2::timestamp
+),
+url_sources AS (
+    SELECT url_key, COUNT(DISTINCT webpage_id) AS sources
+    FROM recent
+    GROUP BY url_key
+),
+title_sources AS (
+    SELECT title_key, COUNT(DISTINCT webpage_id) AS sources
+    FROM recent
+    WHERE length(title_key) >= 20
+    GROUP BY title_key
+)
+UPDATE posts
+SET score = (
+    1
+    + $3::float8 * ln(GREATEST(url_sources.sources, COALESCE(title_sources.sources, 0), 1))
+    + CASE WHEN posts.starred THEN $4::float8 ELSE 0 END
+    + CASE WHEN posts.read THEN $5::float8 ELSE 0 END
+) / power(recent.age_hours + 2, $6::float8)
+FROM recent
+JOIN url_sources ON url_sources.url_key = recent.url_key
+LEFT JOIN title_sources ON title_sources.title_key = recent.title_key
+WHERE posts.id = recent.id
+`
+
+type ScoreRecentPostsParams struct {
+	Now            time.Time
+	WindowStart    time.Time
+	CoverageWeight float64
+	StarWeight     float64
+	ReadWeight     float64
+	Gravity        float64
+}
+
+// Posts score higher the more sources carry the same link or headline and
+// the more the reader has interacted with them, and decay with age.
+func (q *Queries) ScoreRecentPosts(ctx context.Context, arg ScoreRecentPostsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, scoreRecentPosts,
+		arg.Now,
+		arg.WindowStart,
+		arg.CoverageWeight,
+		arg.StarWeight,
+		arg.ReadWeight,
+		arg.Gravity,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setPostRead = `-- name: SetPostRead :one
+UPDATE posts
+SET read = $2
+WHERE id = $1
+RETURNING id, created_at, updated_at, title, description, url, published_at, postname, webpage_id, starred, description_text, author, categories, enclosures, read, score, cluster_id, simhash, url_key, tagged_at
+`
+
+type SetPostReadParams struct {
+	ID   uuid.UUID
+	Read bool
+}
+
+// Reader state leaves updated_at alone; it dates the post's content and
+// drives feed caching.
+func (q *Queries) SetPostRead(ctx context.Context, arg SetPostReadParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, setPostRead, arg.ID, arg.Read)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Description,
+		&i.Url,
+		&i.PublishedAt,
+		&i.Postname,
+		&i.WebpageID,
+		&i.Starred,
+		&i.DescriptionText,
+		&i.Author,
+		&i.Categories,
+		&i.Enclosures,
+		&i.Read,
+		&i.Score,
+		&i.ClusterID,
+		&i.Simhash,
+		&i.UrlKey,
+		&i.TaggedAt,
+	)
+	return i, err
+}
+
+const setPostStarred = `-- name: SetPostStarred :one
+UPDATE posts
+SET starred = $2
+WHERE id = $1
+RETURNING id, created_at, updated_at, title, description, url, published_at, postname, webpage_id, starred, description_text, author, categories, enclosures, read, score, cluster_id, simhash, url_key, tagged_at
+`
+
+type SetPostStarredParams struct {
+	ID      uuid.UUID
+	Starred bool
+}
+
+// Reader state leaves updated_at alone; it dates the post's content and
+// drives feed caching.
+func (q *Queries) SetPostStarred(ctx context.Context, arg SetPostStarredParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, setPostStarred, arg.ID, arg.Starred)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Description,
+		&i.Url,
+		&i.PublishedAt,
+		&i.Postname,
+		&i.WebpageID,
+		&i.Starred,
+		&i.DescriptionText,
+		&i.Author,
+		&i.Categories,
+		&i.Enclosures,
+		&i.Read,
+		&i.Score,
+		&i.ClusterID,
+		&i.Simhash,
+		&i.UrlKey,
+		&i.TaggedAt,
+	)
+	return i, err
+}
+
+const upsertPosts = `-- name: UpsertPosts :many
+INSERT INTO posts (id, created_at, updated_at, title, description, description_text, url, published_at, postName, webpage_id, author, categories, enclosures)
+SELECT
+    unnest($1::uuid[]),
+    $2::timestamp,
+    $2::timestamp,
+    unnest($3::text[]),
+    NULLIF(unnest($4::text[]), ''),
+    unnest($5::text[]),
+    unnest($6::text[]),
+    NULLIF(unnest($7::text[]), '')::timestamp,
+    $8::text,
+    $9::uuid,
+    NULLIF(unnest($10::text[]), ''),
+    unnest($11::text[])::jsonb,
+    unnest($12::text[])::jsonb
+ON CONFLICT (url) DO UPDATE
+SET title = EXCLUDED.title,
+description = EXCLUDED.description,
+description_text = EXCLUDED.description_text,
+author = EXCLUDED.author,
+categories = EXCLUDED.categories,
+enclosures = EXCLUDED.enclosures,
+published_at = COALESCE(EXCLUDED.published_at, posts.published_at),
+updated_at = EXCLUDED.updated_at,
+tagged_at = CASE
+    WHEN posts.title IS DISTINCT FROM EXCLUDED.title
+    OR posts.description_text IS DISTINCT FROM EXCLUDED.description_text
+    OR posts.categories IS DISTINCT FROM EXCLUDED.categories
+    THEN NULL
+    ELSE posts.tagged_at
+END
+WHERE posts.title IS DISTINCT FROM EXCLUDED.title
+OR posts.description IS DISTINCT FROM EXCLUDED.description
+OR posts.author IS DISTINCT FROM EXCLUDED.author
+OR posts.categories IS DISTINCT FROM EXCLUDED.categories
+OR posts.enclosures IS DISTINCT FROM EXCLUDED.enclosures
+RETURNING id, (xmax = 0)::boolean AS inserted
+`
+
+type UpsertPostsParams struct {
+	Ids              []uuid.UUID
+	Now              time.Time
+	Titles           []string
+	Descriptions     []string
+	DescriptionTexts []string
+	Urls             []string
+	PublishedAts     []string
+	Postname         sql.NullString
+	WebpageID        uuid.UUID
+	Authors          []string
+	Categories       []string
+	Enclosures       []string
+}
+
+type UpsertPostsRow struct {
+	ID       uuid.UUID
+	Inserted bool
+}
+
+func (q *Queries) UpsertPosts(ctx context.Context, arg UpsertPostsParams) ([]UpsertPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, upsertPosts,
+		pq.Array(arg.Ids),
+		arg.Now,
+		pq.Array(arg.Titles),
+		pq.Array(arg.Descriptions),
+		pq.Array(arg.DescriptionTexts),
+		pq.Array(arg.Urls),
+		pq.Array(arg.PublishedAts),
+		arg.Postname,
+		arg.WebpageID,
+		pq.Array(arg.Authors),
+		pq.Array(arg.Categories),
+		pq.Array(arg.Enclosures),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UpsertPostsRow
+	for rows.Next() {
+		var i UpsertPostsRow
+		if err := rows.Scan(&i.ID, &i.Inserted); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -7,24 +7,146 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const claimWebpage = `-- name: ClaimWebpage :one
+UPDATE webpages
+SET lease_owner = $1::text,
+lease_expires_at = NOW() + make_interval(secs => $2::float8)
+WHERE id = $3
+AND (
+    $4::boolean
+    OR (
+        (lease_expires_at IS NULL OR lease_expires_at < NOW() OR lease_owner = $1::text)
+        AND (backoff_until IS NULL OR backoff_until < NOW())
+    )
+)
+RETURNING id, created_at, updated_at, name, url, type, last_updated_at, lease_owner, lease_expires_at, last_fetch_status, last_fetch_error, max_item_age_days, user_agent, backoff_until, redirect_url, redirect_count, full_text, site_url
+`
+
+type ClaimWebpageParams struct {
+	LeaseOwner   string
+	LeaseSeconds float64
+	ID           uuid.UUID
+	Force        bool
+}
+
+// Leases one webpage the way ClaimWebpagesToFetch does, returning no row if
+// another worker holds it or it is backing off, unless force is set.
+func (q *Queries) ClaimWebpage(ctx context.Context, arg ClaimWebpageParams) (Webpage, error) {
+	row := q.db.QueryRowContext(ctx, claimWebpage,
+		arg.LeaseOwner,
+		arg.LeaseSeconds,
+		arg.ID,
+		arg.Force,
+	)
+	var i Webpage
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.Type,
+		&i.LastUpdatedAt,
+		&i.LeaseOwner,
+		&i.LeaseExpiresAt,
+		&i.LastFetchStatus,
+		&i.LastFetchError,
+		&i.MaxItemAgeDays,
+		&i.UserAgent,
+		&i.BackoffUntil,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.FullText,
+		&i.SiteUrl,
+	)
+	return i, err
+}
+
+const claimWebpagesToFetch = `-- name: ClaimWebpagesToFetch :many
+UPDATE webpages
+SET lease_owner = $1::text,
+lease_expires_at = NOW() + make_interval(secs => $2::float8)
+WHERE id IN (
+    SELECT id FROM webpages
+    WHERE (lease_expires_at IS NULL OR lease_expires_at < NOW())
+    AND (backoff_until IS NULL OR backoff_until < NOW())
+    ORDER BY last_updated_at ASC NULLS FIRST
+    LIMIT $3
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, name, url, type, last_updated_at, lease_owner, lease_expires_at, last_fetch_status, last_fetch_error, max_item_age_days, user_agent, backoff_until, redirect_url, redirect_count, full_text, site_url
+`
+
+type ClaimWebpagesToFetchParams struct {
+	LeaseOwner   string
+	LeaseSeconds float64
+	MaxWebpages  int32
+}
+
+func (q *Queries) ClaimWebpagesToFetch(ctx context.Context, arg ClaimWebpagesToFetchParams) ([]Webpage, error) {
+	rows, err := q.db.QueryContext(ctx, claimWebpagesToFetch, arg.LeaseOwner, arg.LeaseSeconds, arg.MaxWebpages)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Webpage
+	for rows.Next() {
+		var i Webpage
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.Type,
+			&i.LastUpdatedAt,
+			&i.LeaseOwner,
+			&i.LeaseExpiresAt,
+			&i.LastFetchStatus,
+			&i.LastFetchError,
+			&i.MaxItemAgeDays,
+			&i.UserAgent,
+			&i.BackoffUntil,
+			&i.RedirectUrl,
+			&i.RedirectCount,
+			&i.FullText,
+			&i.SiteUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createWebpage = `-- name: CreateWebpage :one
-INSERT INTO webpages (id, created_at, updated_at, name, url, type)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, created_at, updated_at, name, url, type, last_updated_at
+INSERT INTO webpages (id, created_at, updated_at, name, url, type, max_item_age_days, user_agent, full_text)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, created_at, updated_at, name, url, type, last_updated_at, lease_owner, lease_expires_at, last_fetch_status, last_fetch_error, max_item_age_days, user_agent, backoff_until, redirect_url, redirect_count, full_text, site_url
 `
 
 type CreateWebpageParams struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
-	Url       string
-	Type      string
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Name           string
+	Url            string
+	Type           string
+	MaxItemAgeDays sql.NullInt32
+	UserAgent      sql.NullString
+	FullText       bool
 }
 
 func (q *Queries) CreateWebpage(ctx context.Context, arg CreateWebpageParams) (Webpage, error) {
@@ -35,6 +157,9 @@ func (q *Queries) CreateWebpage(ctx context.Context, arg CreateWebpageParams) (W
 		arg.Name,
 		arg.Url,
 		arg.Type,
+		arg.MaxItemAgeDays,
+		arg.UserAgent,
+		arg.FullText,
 	)
 	var i Webpage
 	err := row.Scan(
@@ -45,12 +170,63 @@ func (q *Queries) CreateWebpage(ctx context.Context, arg CreateWebpageParams) (W
 		&i.Url,
 		&i.Type,
 		&i.LastUpdatedAt,
+		&i.LeaseOwner,
+		&i.LeaseExpiresAt,
+		&i.LastFetchStatus,
+		&i.LastFetchError,
+		&i.MaxItemAgeDays,
+		&i.UserAgent,
+		&i.BackoffUntil,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.FullText,
+		&i.SiteUrl,
 	)
 	return i, err
 }
 
+const createWebpageURLAlias = `-- name: CreateWebpageURLAlias :exec
+INSERT INTO webpage_url_aliases (url, webpage_id, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (url) DO UPDATE SET webpage_id = EXCLUDED.webpage_id, created_at = EXCLUDED.created_at
+`
+
+type CreateWebpageURLAliasParams struct {
+	Url       string
+	WebpageID uuid.UUID
+	CreatedAt time.Time
+}
+
+func (q *Queries) CreateWebpageURLAlias(ctx context.Context, arg CreateWebpageURLAliasParams) error {
+	_, err := q.db.ExecContext(ctx, createWebpageURLAlias, arg.Url, arg.WebpageID, arg.CreatedAt)
+	return err
+}
+
+const deleteWebpage = `-- name: DeleteWebpage :execrows
+DELETE FROM webpages
+WHERE id = $1
+`
+
+func (q *Queries) DeleteWebpage(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteWebpage, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteWebpageURLAlias = `-- name: DeleteWebpageURLAlias :exec
+DELETE FROM webpage_url_aliases
+WHERE url = $1
+`
+
+func (q *Queries) DeleteWebpageURLAlias(ctx context.Context, url string) error {
+	_, err := q.db.ExecContext(ctx, deleteWebpageURLAlias, url)
+	return err
+}
+
 const getNextWebpageToFetch = `-- name: GetNextWebpageToFetch :many
-SELECT id, created_at, updated_at, name, url, type, last_updated_at FROM webpages
+SELECT id, created_at, updated_at, name, url, type, last_updated_at, lease_owner, lease_expires_at, last_fetch_status, last_fetch_error, max_item_age_days, user_agent, backoff_until, redirect_url, redirect_count, full_text, site_url FROM webpages
 ORDER BY last_updated_at ASC NULLS FIRST   
 LIMIT $1
 `
@@ -72,6 +248,187 @@ func (q *Queries) GetNextWebpageToFetch(ctx context.Context, limit int32) ([]Web
 			&i.Url,
 			&i.Type,
 			&i.LastUpdatedAt,
+			&i.LeaseOwner,
+			&i.LeaseExpiresAt,
+			&i.LastFetchStatus,
+			&i.LastFetchError,
+			&i.MaxItemAgeDays,
+			&i.UserAgent,
+			&i.BackoffUntil,
+			&i.RedirectUrl,
+			&i.RedirectCount,
+			&i.FullText,
+			&i.SiteUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebpageByID = `-- name: GetWebpageByID :one
+SELECT id, created_at, updated_at, name, url, type, last_updated_at, lease_owner, lease_expires_at, last_fetch_status, last_fetch_error, max_item_age_days, user_agent, backoff_until, redirect_url, redirect_count, full_text, site_url FROM webpages
+WHERE id = $1
+`
+
+func (q *Queries) GetWebpageByID(ctx context.Context, id uuid.UUID) (Webpage, error) {
+	row := q.db.QueryRowContext(ctx, getWebpageByID, id)
+	var i Webpage
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.Type,
+		&i.LastUpdatedAt,
+		&i.LeaseOwner,
+		&i.LeaseExpiresAt,
+		&i.LastFetchStatus,
+		&i.LastFetchError,
+		&i.MaxItemAgeDays,
+		&i.UserAgent,
+		&i.BackoffUntil,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.FullText,
+		&i.SiteUrl,
+	)
+	return i, err
+}
+
+const getWebpageByURL = `-- name: GetWebpageByURL :one
+SELECT id, created_at, updated_at, name, url, type, last_updated_at, lease_owner, lease_expires_at, last_fetch_status, last_fetch_error, max_item_age_days, user_agent, backoff_until, redirect_url, redirect_count, full_text, site_url FROM webpages
+WHERE url = $1
+`
+
+func (q *Queries) GetWebpageByURL(ctx context.Context, url string) (Webpage, error) {
+	row := q.db.QueryRowContext(ctx, getWebpageByURL, url)
+	var i Webpage
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.Type,
+		&i.LastUpdatedAt,
+		&i.LeaseOwner,
+		&i.LeaseExpiresAt,
+		&i.LastFetchStatus,
+		&i.LastFetchError,
+		&i.MaxItemAgeDays,
+		&i.UserAgent,
+		&i.BackoffUntil,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.FullText,
+		&i.SiteUrl,
+	)
+	return i, err
+}
+
+const getWebpageByURLOrAlias = `-- name: GetWebpageByURLOrAlias :one
+SELECT id, created_at, updated_at, name, url, type, last_updated_at, lease_owner, lease_expires_at, last_fetch_status, last_fetch_error, max_item_age_days, user_agent, backoff_until, redirect_url, redirect_count, full_text, site_url FROM webpages
+WHERE url = $1
+OR id = (SELECT webpage_id FROM webpage_url_aliases WHERE webpage_url_aliases.url = $1)
+`
+
+func (q *Queries) GetWebpageByURLOrAlias(ctx context.Context, url string) (Webpage, error) {
+	row := q.db.QueryRowContext(ctx, getWebpageByURLOrAlias, url)
+	var i Webpage
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.Type,
+		&i.LastUpdatedAt,
+		&i.LeaseOwner,
+		&i.LeaseExpiresAt,
+		&i.LastFetchStatus,
+		&i.LastFetchError,
+		&i.MaxItemAgeDays,
+		&i.UserAgent,
+		&i.BackoffUntil,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.FullText,
+		&i.SiteUrl,
+	)
+	return i, err
+}
+
+const listWebpageURLAliases = `-- name: ListWebpageURLAliases :many
+SELECT url, webpage_id, created_at FROM webpage_url_aliases
+WHERE webpage_id = $1
+ORDER BY created_at DESC
+`
+
+func (q *Queries) ListWebpageURLAliases(ctx context.Context, webpageID uuid.UUID) ([]WebpageUrlAlias, error) {
+	rows, err := q.db.QueryContext(ctx, listWebpageURLAliases, webpageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebpageUrlAlias
+	for rows.Next() {
+		var i WebpageUrlAlias
+		if err := rows.Scan(&i.Url, &i.WebpageID, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWebpages = `-- name: ListWebpages :many
+SELECT id, created_at, updated_at, name, url, type, last_updated_at, lease_owner, lease_expires_at, last_fetch_status, last_fetch_error, max_item_age_days, user_agent, backoff_until, redirect_url, redirect_count, full_text, site_url FROM webpages
+ORDER BY name ASC
+`
+
+func (q *Queries) ListWebpages(ctx context.Context) ([]Webpage, error) {
+	rows, err := q.db.QueryContext(ctx, listWebpages)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Webpage
+	for rows.Next() {
+		var i Webpage
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.Type,
+			&i.LastUpdatedAt,
+			&i.LeaseOwner,
+			&i.LeaseExpiresAt,
+			&i.LastFetchStatus,
+			&i.LastFetchError,
+			&i.MaxItemAgeDays,
+			&i.UserAgent,
+			&i.BackoffUntil,
+			&i.RedirectUrl,
+			&i.RedirectCount,
+			&i.FullText,
+			&i.SiteUrl,
 		); err != nil {
 			return nil, err
 		}
@@ -91,7 +448,7 @@ UPDATE webpages
 SET last_updated_at = Now(), 
 updated_at = Now()
 WHERE id = $1
-RETURNING id, created_at, updated_at, name, url, type, last_updated_at
+RETURNING id, created_at, updated_at, name, url, type, last_updated_at, lease_owner, lease_expires_at, last_fetch_status, last_fetch_error, max_item_age_days, user_agent, backoff_until, redirect_url, redirect_count, full_text, site_url
 `
 
 func (q *Queries) MarkWebpageAsFetched(ctx context.Context, id uuid.UUID) (Webpage, error) {
@@ -105,6 +462,110 @@ func (q *Queries) MarkWebpageAsFetched(ctx context.Context, id uuid.UUID) (Webpa
 		&i.Url,
 		&i.Type,
 		&i.LastUpdatedAt,
+		&i.LeaseOwner,
+		&i.LeaseExpiresAt,
+		&i.LastFetchStatus,
+		&i.LastFetchError,
+		&i.MaxItemAgeDays,
+		&i.UserAgent,
+		&i.BackoffUntil,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.FullText,
+		&i.SiteUrl,
 	)
 	return i, err
 }
+
+const moveWebpageURL = `-- name: MoveWebpageURL :exec
+UPDATE webpages
+SET url = $1,
+redirect_url = NULL,
+redirect_count = 0,
+updated_at = $2
+WHERE id = $3
+`
+
+type MoveWebpageURLParams struct {
+	Url       string
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) MoveWebpageURL(ctx context.Context, arg MoveWebpageURLParams) error {
+	_, err := q.db.ExecContext(ctx, moveWebpageURL, arg.Url, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const recordWebpageFetch = `-- name: RecordWebpageFetch :exec
+UPDATE webpages
+SET last_updated_at = $1::timestamp,
+updated_at = $1::timestamp,
+last_fetch_status = $2::text,
+last_fetch_error = $3::text,
+backoff_until = $4::timestamp,
+site_url = COALESCE($5::text, site_url)
+WHERE id = $6
+`
+
+type RecordWebpageFetchParams struct {
+	FetchedAt    time.Time
+	Status       string
+	Error        sql.NullString
+	BackoffUntil sql.NullTime
+	SiteUrl      sql.NullString
+	ID           uuid.UUID
+}
+
+func (q *Queries) RecordWebpageFetch(ctx context.Context, arg RecordWebpageFetchParams) error {
+	_, err := q.db.ExecContext(ctx, recordWebpageFetch,
+		arg.FetchedAt,
+		arg.Status,
+		arg.Error,
+		arg.BackoffUntil,
+		arg.SiteUrl,
+		arg.ID,
+	)
+	return err
+}
+
+const recordWebpageRedirect = `-- name: RecordWebpageRedirect :one
+UPDATE webpages
+SET redirect_count = CASE
+    WHEN $1::text IS NULL THEN 0
+    WHEN redirect_url = $1::text THEN redirect_count + 1
+    ELSE 1
+END,
+redirect_url = $1::text
+WHERE id = $2
+RETURNING redirect_count
+`
+
+type RecordWebpageRedirectParams struct {
+	RedirectUrl sql.NullString
+	ID          uuid.UUID
+}
+
+func (q *Queries) RecordWebpageRedirect(ctx context.Context, arg RecordWebpageRedirectParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, recordWebpageRedirect, arg.RedirectUrl, arg.ID)
+	var redirect_count int32
+	err := row.Scan(&redirect_count)
+	return redirect_count, err
+}
+
+const releaseWebpageLease = `-- name: ReleaseWebpageLease :exec
+UPDATE webpages
+SET lease_owner = NULL,
+lease_expires_at = NULL
+WHERE id = $1 AND lease_owner = $2
+`
+
+type ReleaseWebpageLeaseParams struct {
+	ID         uuid.UUID
+	LeaseOwner sql.NullString
+}
+
+func (q *Queries) ReleaseWebpageLease(ctx context.Context, arg ReleaseWebpageLeaseParams) error {
+	_, err := q.db.ExecContext(ctx, releaseWebpageLease, arg.ID, arg.LeaseOwner)
+	return err
+}
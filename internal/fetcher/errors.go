@@ -0,0 +1,51 @@
+package fetcher
+
+import (
+	"fmt"
+	"time"
+)
+
+// HTTPStatusError reports a request answered with a non-2xx status.
+type HTTPStatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	// RetryAfter is how long the host asked us to wait before the next
+	// request, from a 429 or 503 response. Zero when it did not ask.
+	RetryAfter time.Duration
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("request to %s failed: %s", e.URL, e.Status)
+}
+
+// BodyTooLargeError reports a body larger than the configured limit.
+type BodyTooLargeError struct {
+	URL   string
+	Limit int64
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("response from %s exceeds the %d byte limit", e.URL, e.Limit)
+}
+
+// RobotsDisallowedError reports a URL that robots.txt does not allow us to
+// fetch.
+type RobotsDisallowedError struct {
+	URL string
+}
+
+func (e *RobotsDisallowedError) Error() string {
+	return fmt.Sprintf("robots.txt disallows fetching %s", e.URL)
+}
+
+// HostBackoffError reports a request that was not made because the host
+// asked us, through Retry-After, to wait until Until.
+type HostBackoffError struct {
+	Host  string
+	Until time.Time
+}
+
+func (e *HostBackoffError) Error() string {
+	return fmt.Sprintf("backing off from %s until %s", e.Host, e.Until.UTC().Format(time.RFC3339))
+}
@@ -0,0 +1,357 @@
+// Package fetcher performs every outbound HTTP request the application
+// makes, through one shared client so connections are reused and limits
+// apply across all feeds.
+package fetcher
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+// DefaultUserAgent identifies the application honestly, so publishers can
+// tell who is reading their feeds.
+const DefaultUserAgent = "dailyread/1.0 (+https://github.com/cyberkillua/dailyread)"
+
+// DefaultMaxBodySize is the largest body, after decompression, that is
+// read when no other limit is configured.
+const DefaultMaxBodySize = 10 << 20
+
+const (
+	// defaultBackoff is how long a host that answers 429 without a
+	// Retry-After header is left alone.
+	defaultBackoff = time.Minute
+	// maxBackoff caps the wait a Retry-After header can impose.
+	maxBackoff = 24 * time.Hour
+)
+
+// Options configure a Fetcher. Zero values select the defaults.
+type Options struct {
+	// UserAgent is sent unless a request sets its own.
+	UserAgent string
+	// Timeout bounds a whole request, including reading the body.
+	Timeout time.Duration
+	// MaxRedirects is the number of redirects followed before giving up.
+	MaxRedirects int
+	// MaxConnsPerHost limits concurrent connections to a single host.
+	MaxConnsPerHost int
+	// HostConcurrency limits concurrent requests to a single host, however
+	// many feeds it serves.
+	HostConcurrency int
+	// HostInterval is the minimum time between the starts of two requests
+	// to the same host. Negative disables the spacing.
+	HostInterval time.Duration
+	// IgnoreRobots skips the robots.txt check.
+	IgnoreRobots bool
+	// MaxBodySize is the largest body, after decompression, that is read.
+	MaxBodySize int64
+	// Proxy routes every request through this URL. When nil the standard
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY variables apply.
+	Proxy *url.URL
+	// Transport replaces the default transport, for example with one that
+	// talks to an httptest server. Proxy and MaxConnsPerHost are then the
+	// transport's responsibility.
+	Transport http.RoundTripper
+}
+
+// Fetcher is safe for concurrent use. It is polite to the hosts it
+// fetches from: requests to one host are limited and spaced out, hosts
+// that answer with Retry-After are left alone for that long, and URLs
+// disallowed by robots.txt are not fetched.
+type Fetcher struct {
+	client       *http.Client
+	maxRedirects int
+	userAgent    string
+	maxBodySize  int64
+	hosts        *hostGate
+	robots       *robotsCache
+	ignoreRobots bool
+}
+
+func New(opts Options) *Fetcher {
+	if opts.UserAgent == "" {
+		opts.UserAgent = DefaultUserAgent
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	if opts.MaxRedirects <= 0 {
+		opts.MaxRedirects = 5
+	}
+	if opts.MaxConnsPerHost <= 0 {
+		opts.MaxConnsPerHost = 4
+	}
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = DefaultMaxBodySize
+	}
+	if opts.HostConcurrency <= 0 {
+		opts.HostConcurrency = 2
+	}
+	if opts.HostInterval == 0 {
+		opts.HostInterval = time.Second
+	} else if opts.HostInterval < 0 {
+		opts.HostInterval = 0
+	}
+
+	transport := opts.Transport
+	if transport == nil {
+		proxy := http.ProxyFromEnvironment
+		if opts.Proxy != nil {
+			proxy = http.ProxyURL(opts.Proxy)
+		}
+		transport = &http.Transport{
+			Proxy: proxy,
+			DialContext: (&net.Dialer{
+				Timeout:   10 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			MaxIdleConnsPerHost:   opts.MaxConnsPerHost,
+			MaxConnsPerHost:       opts.MaxConnsPerHost,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: opts.Timeout,
+			// Compression is negotiated and decoded by Get so that brotli
+			// is supported alongside gzip.
+			DisableCompression: true,
+		}
+	}
+
+	return &Fetcher{
+		client: &http.Client{
+			Timeout:   opts.Timeout,
+			Transport: transport,
+		},
+		maxRedirects: opts.MaxRedirects,
+		userAgent:    opts.UserAgent,
+		maxBodySize:  opts.MaxBodySize,
+		hosts:        newHostGate(opts.HostConcurrency, opts.HostInterval),
+		robots:       newRobotsCache(),
+		ignoreRobots: opts.IgnoreRobots,
+	}
+}
+
+// Request describes a GET request.
+type Request struct {
+	URL string
+	// Accept is sent as the Accept header when set.
+	Accept string
+	// UserAgent overrides the fetcher's User-Agent for this request.
+	UserAgent string
+}
+
+// Response is a successful response. Body is already decompressed and
+// returns a *BodyTooLargeError once the size limit is exceeded; the caller
+// must close it.
+type Response struct {
+	// URL is the final URL after redirects.
+	URL string
+	// Redirects lists the redirects that were followed, in order.
+	Redirects []Redirect
+	// PermanentURL is where the requested URL has moved to for good: the
+	// target of the leading run of permanent redirects (301 or 308). It is
+	// empty when the first redirect, if any, was temporary.
+	PermanentURL string
+	StatusCode   int
+	Header       http.Header
+	Body         io.ReadCloser
+}
+
+// Redirect is one hop of a redirect chain.
+type Redirect struct {
+	From       string
+	To         string
+	StatusCode int
+}
+
+// Permanent reports whether the redirect was 301 Moved Permanently or 308
+// Permanent Redirect.
+func (r Redirect) Permanent() bool {
+	return r.StatusCode == http.StatusMovedPermanently || r.StatusCode == http.StatusPermanentRedirect
+}
+
+// Get fetches req.URL. Responses with a non-2xx status are returned as an
+// *HTTPStatusError and URLs robots.txt disallows as a
+// *RobotsDisallowedError.
+func (f *Fetcher) Get(ctx context.Context, req Request) (*Response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, req.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	host := httpReq.URL.Host
+
+	userAgent := f.userAgent
+	if req.UserAgent != "" {
+		userAgent = req.UserAgent
+	}
+	httpReq.Header.Set("User-Agent", userAgent)
+	if req.Accept != "" {
+		httpReq.Header.Set("Accept", req.Accept)
+	}
+	httpReq.Header.Set("Accept-Encoding", "gzip, br")
+
+	release, err := f.hosts.acquire(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	// The slot is held until the body is closed, not just until the
+	// headers arrive.
+	handedOff := false
+	defer func() {
+		if !handedOff {
+			release()
+		}
+	}()
+
+	if !f.ignoreRobots {
+		allowed, err := f.robotsAllowed(ctx, httpReq.URL, userAgent)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, &RobotsDisallowedError{URL: req.URL}
+		}
+	}
+
+	// Each request records its own redirect chain, on a copy of the shared
+	// client that still uses the shared transport.
+	var redirects []Redirect
+	client := *f.client
+	client.CheckRedirect = func(next *http.Request, via []*http.Request) error {
+		if len(via) > f.maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", f.maxRedirects)
+		}
+		hop := Redirect{From: via[len(via)-1].URL.String(), To: next.URL.String()}
+		if next.Response != nil {
+			hop.StatusCode = next.Response.StatusCode
+		}
+		redirects = append(redirects, hop)
+		slog.Debug("Following redirect", "from", hop.From, "to", hop.To, "status", hop.StatusCode)
+		return nil
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", req.URL, err)
+	}
+
+	slog.Debug("Fetched URL",
+		"url", req.URL,
+		"status", resp.StatusCode,
+		"content_type", resp.Header.Get("Content-Type"),
+		"content_encoding", resp.Header.Get("Content-Encoding"),
+	)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		wait := min(retryAfter(resp, time.Now(), defaultBackoff), maxBackoff)
+		if wait > 0 {
+			slog.Info("Host asked us to back off", "host", host, "status", resp.StatusCode, "retry_after", wait)
+			f.hosts.backOff(host, time.Now().Add(wait))
+		}
+		return nil, &HTTPStatusError{URL: req.URL, StatusCode: resp.StatusCode, Status: resp.Status, RetryAfter: wait}
+	}
+
+	if resp.ContentLength > f.maxBodySize && resp.Header.Get("Content-Encoding") == "" {
+		resp.Body.Close()
+		return nil, &BodyTooLargeError{URL: req.URL, Limit: f.maxBodySize}
+	}
+
+	body, err := decodeBody(resp)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	handedOff = true
+	return &Response{
+		URL:          resp.Request.URL.String(),
+		Redirects:    redirects,
+		PermanentURL: permanentURL(redirects),
+		StatusCode:   resp.StatusCode,
+		Header:       resp.Header,
+		Body: &limitedBody{
+			r:         body,
+			closer:    resp.Body,
+			release:   release,
+			remaining: f.maxBodySize,
+			err:       &BodyTooLargeError{URL: req.URL, Limit: f.maxBodySize},
+		},
+	}, nil
+}
+
+// permanentURL follows the redirects while they are permanent.
+func permanentURL(redirects []Redirect) string {
+	moved := ""
+	for _, hop := range redirects {
+		if !hop.Permanent() {
+			break
+		}
+		moved = hop.To
+	}
+	return moved
+}
+
+// decodeBody undoes the response's Content-Encoding. The size limit is
+// applied to what it returns, so a small compressed body cannot expand
+// without bound.
+func decodeBody(resp *http.Response) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+		return resp.Body, nil
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gzip body: %w", err)
+		}
+		return zr, nil
+	case "br":
+		return brotli.NewReader(resp.Body), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", resp.Header.Get("Content-Encoding"))
+	}
+}
+
+// limitedBody fails with err once more than remaining bytes are read, and
+// frees the host slot when closed.
+type limitedBody struct {
+	r         io.Reader
+	closer    io.Closer
+	release   func()
+	once      sync.Once
+	remaining int64
+	err       error
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, b.err
+	}
+	// Read one byte past the limit to tell a body of exactly the limit
+	// from one that is too large.
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.r.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n + int(b.remaining), b.err
+	}
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	b.once.Do(b.release)
+	return b.closer.Close()
+}
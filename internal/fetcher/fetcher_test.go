@@ -0,0 +1,366 @@
+package fetcher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func newTestFetcher(opts Options) *Fetcher {
+	opts.HostInterval = -1
+	opts.IgnoreRobots = true
+	return New(opts)
+}
+
+func get(t *testing.T, f *Fetcher, url string) ([]byte, error) {
+	t.Helper()
+	resp, err := f.Get(context.Background(), Request{URL: url})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+func TestGetDecodesBody(t *testing.T) {
+	const body = "<rss><channel><title>Compressed</title></channel></rss>"
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(body))
+	zw.Close()
+
+	var br bytes.Buffer
+	bw := brotli.NewWriter(&br)
+	bw.Write([]byte(body))
+	bw.Close()
+
+	tests := []struct {
+		encoding string
+		data     []byte
+	}{
+		{"", []byte(body)},
+		{"identity", []byte(body)},
+		{"gzip", gz.Bytes()},
+		{"x-gzip", gz.Bytes()},
+		{"GZIP", gz.Bytes()},
+		{"br", br.Bytes()},
+	}
+	for _, tt := range tests {
+		t.Run("encoding "+tt.encoding, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Accept-Encoding"); got != "gzip, br" {
+					t.Errorf("Accept-Encoding = %q", got)
+				}
+				if tt.encoding != "" {
+					w.Header().Set("Content-Encoding", tt.encoding)
+				}
+				w.Write(tt.data)
+			}))
+			defer srv.Close()
+
+			got, err := get(t, newTestFetcher(Options{}), srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != body {
+				t.Errorf("body = %q, want %q", got, body)
+			}
+		})
+	}
+}
+
+func TestGetRejectsUnknownEncoding(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "compress")
+		w.Write([]byte("data"))
+	}))
+	defer srv.Close()
+
+	if _, err := get(t, newTestFetcher(Options{}), srv.URL); err == nil {
+		t.Error("Get succeeded for an unsupported encoding")
+	}
+}
+
+func TestGetBodySizeLimit(t *testing.T) {
+	const limit = 1024
+
+	var bomb bytes.Buffer
+	zw := gzip.NewWriter(&bomb)
+	zw.Write(bytes.Repeat([]byte("a"), 100*limit))
+	zw.Close()
+
+	tests := []struct {
+		name     string
+		encoding string
+		data     []byte
+		chunked  bool
+		tooLarge bool
+	}{
+		{"exactly the limit", "", bytes.Repeat([]byte("a"), limit), false, false},
+		{"declared length over the limit", "", bytes.Repeat([]byte("a"), limit+1), false, true},
+		{"chunked body over the limit", "", bytes.Repeat([]byte("a"), 4*limit), true, true},
+		{"small gzip body expanding past the limit", "gzip", bomb.Bytes(), false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.encoding != "" {
+					w.Header().Set("Content-Encoding", tt.encoding)
+				}
+				if tt.chunked {
+					// Flushing before the body is written leaves the length
+					// unknown to the client.
+					w.(http.Flusher).Flush()
+				}
+				w.Write(tt.data)
+			}))
+			defer srv.Close()
+
+			got, err := get(t, newTestFetcher(Options{MaxBodySize: limit}), srv.URL)
+			var tooLarge *BodyTooLargeError
+			if tt.tooLarge {
+				if !errors.As(err, &tooLarge) {
+					t.Fatalf("Get = %v, want *BodyTooLargeError", err)
+				}
+				if tooLarge.Limit != limit {
+					t.Errorf("limit = %d, want %d", tooLarge.Limit, limit)
+				}
+				if len(got) > limit {
+					t.Errorf("read %d bytes past a limit of %d", len(got), limit)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != limit {
+				t.Errorf("read %d bytes, want %d", len(got), limit)
+			}
+		})
+	}
+}
+
+func TestGetStatusError(t *testing.T) {
+	for _, status := range []int{http.StatusNotFound, http.StatusGone, http.StatusInternalServerError, http.StatusNotModified} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+
+		_, err := get(t, newTestFetcher(Options{}), srv.URL+"/feed")
+		var statusErr *HTTPStatusError
+		if !errors.As(err, &statusErr) {
+			t.Errorf("status %d: Get = %v, want *HTTPStatusError", status, err)
+		} else if statusErr.StatusCode != status || statusErr.URL != srv.URL+"/feed" {
+			t.Errorf("status %d: got %d for %s", status, statusErr.StatusCode, statusErr.URL)
+		}
+		srv.Close()
+	}
+}
+
+func TestGetHeaders(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      Options
+		req       Request
+		userAgent string
+		accept    string
+	}{
+		{"defaults", Options{}, Request{}, DefaultUserAgent, ""},
+		{"configured user agent", Options{UserAgent: "custom/2.0"}, Request{}, "custom/2.0", ""},
+		{"request overrides", Options{UserAgent: "custom/2.0"}, Request{UserAgent: "feedbot/1.0", Accept: "application/rss+xml"}, "feedbot/1.0", "application/rss+xml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("User-Agent"); got != tt.userAgent {
+					t.Errorf("User-Agent = %q, want %q", got, tt.userAgent)
+				}
+				if got := r.Header.Get("Accept"); got != tt.accept {
+					t.Errorf("Accept = %q, want %q", got, tt.accept)
+				}
+			}))
+			defer srv.Close()
+
+			req := tt.req
+			req.URL = srv.URL
+			resp, err := newTestFetcher(tt.opts).Get(context.Background(), req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+		})
+	}
+}
+
+func TestGetReleasesHostOnClose(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 10)))
+	}))
+	defer srv.Close()
+
+	// With one slot per host, a second Get can only start once the first
+	// body is closed.
+	f := newTestFetcher(Options{HostConcurrency: 1})
+	for i := 0; i < 3; i++ {
+		resp, err := f.Get(context.Background(), Request{URL: srv.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		// Closing twice must not release the slot twice.
+		resp.Body.Close()
+	}
+	if n := len(f.hosts.state(strings.TrimPrefix(srv.URL, "http://")).slots); n != 0 {
+		t.Errorf("%d slots still held after every body was closed", n)
+	}
+}
+
+func TestPermanentURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		redirects []Redirect
+		want      string
+	}{
+		{"no redirects", nil, ""},
+		{"one permanent", []Redirect{{To: "https://b/", StatusCode: http.StatusMovedPermanently}}, "https://b/"},
+		{"permanent chain", []Redirect{
+			{To: "https://b/", StatusCode: http.StatusMovedPermanently},
+			{To: "https://c/", StatusCode: http.StatusPermanentRedirect},
+		}, "https://c/"},
+		{"temporary first", []Redirect{
+			{To: "https://b/", StatusCode: http.StatusFound},
+			{To: "https://c/", StatusCode: http.StatusMovedPermanently},
+		}, ""},
+		{"temporary later", []Redirect{
+			{To: "https://b/", StatusCode: http.StatusMovedPermanently},
+			{To: "https://c/", StatusCode: http.StatusTemporaryRedirect},
+			{To: "https://d/", StatusCode: http.StatusMovedPermanently},
+		}, "https://b/"},
+	}
+	for _, tt := range tests {
+		if got := permanentURL(tt.redirects); got != tt.want {
+			t.Errorf("%s: permanentURL = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGetFollowsRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/old", http.RedirectHandler("/moved", http.StatusMovedPermanently))
+	mux.Handle("/moved", http.RedirectHandler("/new", http.StatusPermanentRedirect))
+	mux.Handle("/temporary", http.RedirectHandler("/new", http.StatusFound))
+	mux.Handle("/mixed", http.RedirectHandler("/temporary", http.StatusMovedPermanently))
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("feed"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := []struct {
+		path      string
+		hops      []int
+		permanent string
+	}{
+		{"/new", nil, ""},
+		{"/old", []int{http.StatusMovedPermanently, http.StatusPermanentRedirect}, "/new"},
+		{"/temporary", []int{http.StatusFound}, ""},
+		{"/mixed", []int{http.StatusMovedPermanently, http.StatusFound}, "/temporary"},
+	}
+	f := newTestFetcher(Options{})
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			resp, err := f.Get(context.Background(), Request{URL: srv.URL + tt.path})
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.URL != srv.URL+"/new" {
+				t.Errorf("final URL = %q", resp.URL)
+			}
+			if len(resp.Redirects) != len(tt.hops) {
+				t.Fatalf("got %d redirects, want %d", len(resp.Redirects), len(tt.hops))
+			}
+			for i, hop := range resp.Redirects {
+				if hop.StatusCode != tt.hops[i] {
+					t.Errorf("redirect %d status = %d, want %d", i, hop.StatusCode, tt.hops[i])
+				}
+			}
+			want := ""
+			if tt.permanent != "" {
+				want = srv.URL + tt.permanent
+			}
+			if resp.PermanentURL != want {
+				t.Errorf("PermanentURL = %q, want %q", resp.PermanentURL, want)
+			}
+		})
+	}
+}
+
+func TestGetRedirectLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Path+"x", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	f := newTestFetcher(Options{MaxRedirects: 2})
+	if _, err := get(t, f, srv.URL+"/a"); err == nil || !strings.Contains(err.Error(), "stopped after 2 redirects") {
+		t.Errorf("Get = %v, want the redirect limit error", err)
+	}
+}
+
+func TestGetThroughProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A forward proxy is sent the absolute URL.
+		proxied = r.URL.String()
+		w.Write([]byte("via proxy"))
+	}))
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	f := newTestFetcher(Options{Proxy: proxyURL})
+	got, err := get(t, f, "http://feeds.example.invalid/rss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "via proxy" || proxied != "http://feeds.example.invalid/rss" {
+		t.Errorf("proxy saw %q and returned %q", proxied, got)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestGetCustomTransport(t *testing.T) {
+	var requests int
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		requests++
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader("stubbed")),
+			Request:    r,
+		}, nil
+	})
+
+	f := newTestFetcher(Options{Transport: transport})
+	got, err := get(t, f, "https://example.invalid/feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "stubbed" || requests != 1 {
+		t.Errorf("got %q after %d requests", got, requests)
+	}
+}
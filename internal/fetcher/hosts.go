@@ -0,0 +1,126 @@
+package fetcher
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hostGate keeps requests to a single host polite: at most concurrency at
+// a time, spaced at least interval apart, and none while the host has
+// asked us to back off.
+type hostGate struct {
+	concurrency int
+	interval    time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+type hostState struct {
+	slots        chan struct{}
+	next         time.Time
+	backoffUntil time.Time
+}
+
+func newHostGate(concurrency int, interval time.Duration) *hostGate {
+	return &hostGate{
+		concurrency: concurrency,
+		interval:    interval,
+		hosts:       make(map[string]*hostState),
+	}
+}
+
+func (g *hostGate) state(host string) *hostState {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	st, ok := g.hosts[host]
+	if !ok {
+		st = &hostState{slots: make(chan struct{}, g.concurrency)}
+		g.hosts[host] = st
+	}
+	return st
+}
+
+// acquire waits for a free slot and the host's next turn. The returned
+// function must be called once the request has finished. A host that has
+// asked us to back off fails with a *HostBackoffError straight away rather
+// than tying up the caller until the backoff ends.
+func (g *hostGate) acquire(ctx context.Context, host string) (release func(), err error) {
+	st := g.state(host)
+
+	g.mu.Lock()
+	until := st.backoffUntil
+	g.mu.Unlock()
+	if time.Now().Before(until) {
+		return nil, &HostBackoffError{Host: host, Until: until}
+	}
+
+	select {
+	case st.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release = func() { <-st.slots }
+
+	g.mu.Lock()
+	now := time.Now()
+	at := now
+	if st.next.After(at) {
+		at = st.next
+	}
+	st.next = at.Add(g.interval)
+	g.mu.Unlock()
+
+	if wait := time.Until(at); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// backOff holds every request to host until until.
+func (g *hostGate) backOff(host string, until time.Time) {
+	st := g.state(host)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if until.After(st.backoffUntil) {
+		st.backoffUntil = until
+	}
+}
+
+// retryAfter reads the Retry-After header of a 429 or 503 response, which
+// is either a number of seconds or an HTTP date. A 429 without the header
+// is still a request to slow down, so it gets defaultBackoff.
+func retryAfter(resp *http.Response, now time.Time, defaultBackoff time.Duration) time.Duration {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0
+	}
+
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := date.Sub(now); d > 0 {
+			return d
+		}
+		return 0
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return defaultBackoff
+	}
+	return 0
+}
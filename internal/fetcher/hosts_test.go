@@ -0,0 +1,146 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		status int
+		header string
+		want   time.Duration
+	}{
+		{"seconds", http.StatusTooManyRequests, "120", 2 * time.Minute},
+		{"seconds with spaces", http.StatusServiceUnavailable, " 30 ", 30 * time.Second},
+		{"zero seconds", http.StatusTooManyRequests, "0", 0},
+		{"http date", http.StatusServiceUnavailable, "Tue, 02 Jan 2024 15:14:05 GMT", 10 * time.Minute},
+		{"rfc 850 date", http.StatusTooManyRequests, "Tuesday, 02-Jan-24 15:05:05 GMT", time.Minute},
+		{"ansi c date", http.StatusTooManyRequests, "Tue Jan  2 15:04:35 2024", 30 * time.Second},
+		{"date in the past", http.StatusServiceUnavailable, "Tue, 02 Jan 2024 15:00:00 GMT", 0},
+		{"429 without header", http.StatusTooManyRequests, "", defaultBackoff},
+		{"429 with unreadable header", http.StatusTooManyRequests, "soon", defaultBackoff},
+		{"429 with negative seconds", http.StatusTooManyRequests, "-5", defaultBackoff},
+		{"503 without header", http.StatusServiceUnavailable, "", 0},
+		{"other status ignores header", http.StatusInternalServerError, "120", 0},
+		{"success ignores header", http.StatusOK, "120", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			if tt.header != "" {
+				resp.Header.Set("Retry-After", tt.header)
+			}
+			if got := retryAfter(resp, now, defaultBackoff); got != tt.want {
+				t.Errorf("retryAfter = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHostGateConcurrency(t *testing.T) {
+	g := newHostGate(2, 0)
+	ctx := context.Background()
+
+	release1, err := g.acquire(ctx, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release2, err := g.acquire(ctx, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another host has its own slots.
+	other, err := g.acquire(ctx, "example.org")
+	if err != nil {
+		t.Fatalf("acquire for another host: %v", err)
+	}
+	other()
+
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := g.acquire(short, "example.com"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("third acquire = %v, want a deadline error", err)
+	}
+
+	release1()
+	release3, err := g.acquire(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+	release2()
+	release3()
+}
+
+func TestHostGateInterval(t *testing.T) {
+	const interval = 50 * time.Millisecond
+	g := newHostGate(4, interval)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := g.acquire(ctx, "example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed < 2*interval {
+		t.Errorf("three requests took %s, want at least %s", elapsed, 2*interval)
+	}
+}
+
+func TestHostGateBackOff(t *testing.T) {
+	g := newHostGate(1, 0)
+	until := time.Now().Add(time.Hour)
+	g.backOff("example.com", until)
+	// An earlier deadline does not shorten the backoff.
+	g.backOff("example.com", time.Now().Add(time.Minute))
+
+	_, err := g.acquire(context.Background(), "example.com")
+	var backoff *HostBackoffError
+	if !errors.As(err, &backoff) {
+		t.Fatalf("acquire = %v, want *HostBackoffError", err)
+	}
+	if !backoff.Until.Equal(until) {
+		t.Errorf("backoff until %s, want %s", backoff.Until, until)
+	}
+
+	release, err := g.acquire(context.Background(), "example.org")
+	if err != nil {
+		t.Fatalf("acquire for another host: %v", err)
+	}
+	release()
+}
+
+func TestGetBacksOffAfterRetryAfter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	f := New(Options{HostInterval: -1, IgnoreRobots: true})
+	_, err := f.Get(context.Background(), Request{URL: srv.URL + "/feed"})
+	var status *HTTPStatusError
+	if !errors.As(err, &status) {
+		t.Fatalf("Get = %v, want *HTTPStatusError", err)
+	}
+	if status.StatusCode != http.StatusTooManyRequests || status.RetryAfter != time.Hour {
+		t.Errorf("status error = %d, retry after %s", status.StatusCode, status.RetryAfter)
+	}
+
+	_, err = f.Get(context.Background(), Request{URL: srv.URL + "/other"})
+	var backoff *HostBackoffError
+	if !errors.As(err, &backoff) {
+		t.Errorf("second Get = %v, want *HostBackoffError", err)
+	}
+}
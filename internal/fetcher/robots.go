@@ -0,0 +1,252 @@
+package fetcher
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// robotsTTL is how long a fetched robots.txt is trusted.
+	robotsTTL = 24 * time.Hour
+	// robotsErrorTTL is how long an unreachable robots.txt blocks a host
+	// before it is tried again.
+	robotsErrorTTL = time.Hour
+	// maxRobotsSize is the part of a robots.txt that is parsed, the minimum
+	// RFC 9309 requires.
+	maxRobotsSize = 500 << 10
+)
+
+// robotsCache fetches and caches robots.txt per origin.
+type robotsCache struct {
+	mu      sync.Mutex
+	entries map[string]*robotsEntry
+}
+
+type robotsEntry struct {
+	// ready is closed once rules and expires are set.
+	ready   chan struct{}
+	rules   *robotsRules
+	expires time.Time
+}
+
+func newRobotsCache() *robotsCache {
+	return &robotsCache{entries: make(map[string]*robotsEntry)}
+}
+
+// robotsAllowed reports whether userAgent may fetch target. Concurrent
+// callers for the same origin share a single robots.txt request.
+func (f *Fetcher) robotsAllowed(ctx context.Context, target *url.URL, userAgent string) (bool, error) {
+	origin := target.Scheme + "://" + target.Host
+
+	c := f.robots
+	c.mu.Lock()
+	entry, ok := c.entries[origin]
+	if !ok || (isClosed(entry.ready) && time.Now().After(entry.expires)) {
+		entry = &robotsEntry{ready: make(chan struct{})}
+		c.entries[origin] = entry
+		c.mu.Unlock()
+
+		entry.rules, entry.expires = f.fetchRobots(ctx, origin)
+		close(entry.ready)
+	} else {
+		c.mu.Unlock()
+	}
+
+	select {
+	case <-entry.ready:
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+
+	path := target.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if target.RawQuery != "" {
+		path += "?" + target.RawQuery
+	}
+	return entry.rules.allowed(productToken(userAgent), path), nil
+}
+
+// fetchRobots follows RFC 9309: a missing robots.txt (any 4xx) allows
+// everything, while a server error or network failure disallows
+// everything until it is retried.
+func (f *Fetcher) fetchRobots(ctx context.Context, origin string) (*robotsRules, time.Time) {
+	now := time.Now()
+
+	// The result is shared with other callers, so it must not depend on
+	// this caller giving up; the client timeout still bounds the request.
+	ctx = context.WithoutCancel(ctx)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, origin+"/robots.txt", nil)
+	if err != nil {
+		return &robotsRules{disallowAll: true}, now.Add(robotsErrorTTL)
+	}
+	req.Header.Set("User-Agent", f.userAgent)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return &robotsRules{disallowAll: true}, now.Add(robotsErrorTTL)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		return parseRobots(io.LimitReader(resp.Body, maxRobotsSize)), now.Add(robotsTTL)
+	case resp.StatusCode >= 400 && resp.StatusCode <= 499:
+		return &robotsRules{}, now.Add(robotsTTL)
+	default:
+		return &robotsRules{disallowAll: true}, now.Add(robotsErrorTTL)
+	}
+}
+
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// productToken returns the lower-cased name at the start of a User-Agent,
+// e.g. "dailyread" for "dailyread/1.0 (+https://...)".
+func productToken(userAgent string) string {
+	token := userAgent
+	if i := strings.IndexAny(token, "/ "); i >= 0 {
+		token = token[:i]
+	}
+	return strings.ToLower(token)
+}
+
+// robotsRules holds the groups of a parsed robots.txt.
+type robotsRules struct {
+	disallowAll bool
+	groups      []robotsGroup
+}
+
+type robotsGroup struct {
+	agents []string
+	rules  []robotsRule
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+func parseRobots(r io.Reader) *robotsRules {
+	rules := &robotsRules{}
+	var current *robotsGroup
+	// Consecutive user-agent lines share the group that follows them.
+	inAgents := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				rules.groups = append(rules.groups, robotsGroup{})
+				current = &rules.groups[len(rules.groups)-1]
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			inAgents = true
+		case "allow", "disallow":
+			inAgents = false
+			if current == nil || value == "" {
+				// An empty disallow allows everything, which is already
+				// the default.
+				continue
+			}
+			current.rules = append(current.rules, robotsRule{allow: key == "allow", pattern: value})
+		default:
+			inAgents = false
+		}
+	}
+	return rules
+}
+
+// allowed applies the group for agent, or the "*" group when there is
+// none. The longest matching rule wins and allow wins ties.
+func (r *robotsRules) allowed(agent, path string) bool {
+	if r.disallowAll {
+		return false
+	}
+
+	matched, found := r.rulesFor(agent)
+	if !found {
+		matched, _ = r.rulesFor("*")
+	}
+
+	best, allow := -1, true
+	for _, rule := range matched {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > best || (len(rule.pattern) == best && rule.allow) {
+			best, allow = len(rule.pattern), rule.allow
+		}
+	}
+	return allow
+}
+
+// rulesFor collects the rules of every group naming agent.
+func (r *robotsRules) rulesFor(agent string) (rules []robotsRule, found bool) {
+	for _, group := range r.groups {
+		for _, name := range group.agents {
+			if name == agent {
+				rules = append(rules, group.rules...)
+				found = true
+			}
+		}
+	}
+	return rules, found
+}
+
+// robotsMatch matches a path against a robots.txt pattern, where "*"
+// matches any sequence and a trailing "$" anchors the end.
+func robotsMatch(pattern, path string) bool {
+	if strings.HasSuffix(pattern, "$") {
+		pattern = strings.TrimSuffix(pattern, "$")
+	} else {
+		pattern += "*"
+	}
+
+	p, s := 0, 0
+	star, resume := -1, 0
+	for s < len(path) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			star, resume = p, s
+			p++
+		case p < len(pattern) && pattern[p] == path[s]:
+			p++
+			s++
+		case star >= 0:
+			resume++
+			p, s = star+1, resume
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
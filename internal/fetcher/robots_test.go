@@ -0,0 +1,192 @@
+package fetcher
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRobotsMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"/", "/", true},
+		{"/", "/anything", true},
+		{"/private", "/private", true},
+		{"/private", "/private/page", true},
+		{"/private", "/privateer", true},
+		{"/private", "/public", false},
+		{"/private/", "/private", false},
+		{"/*.pdf", "/docs/report.pdf", true},
+		{"/*.pdf", "/docs/report.pdf?download=1", true},
+		{"/*.pdf$", "/docs/report.pdf", true},
+		{"/*.pdf$", "/docs/report.pdf?download=1", false},
+		{"/feed$", "/feed", true},
+		{"/feed$", "/feed/", false},
+		{"/a*b*c", "/axxbyyc", true},
+		{"/a*b*c", "/axxcyyb", false},
+		{"/a*c", "/abcbc", true},
+		{"/*", "/", true},
+		{"*", "/anything", true},
+		{"/search?q=", "/search?q=go", true},
+		{"/search?q=", "/search", false},
+		{"/Private", "/private", false},
+	}
+	for _, tt := range tests {
+		if got := robotsMatch(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("robotsMatch(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRobotsAllowed(t *testing.T) {
+	const robots = `
+# Everyone else
+User-agent: *
+Disallow: /private
+Allow: /private/public
+Disallow: /*.pdf$
+
+User-agent: dailyread
+User-agent: otherbot
+Disallow: /drafts/   # trailing comment
+Allow: /drafts/published
+
+User-agent: blockedbot
+Disallow: /
+
+User-agent: emptybot
+Disallow:
+
+user-agent: DailyRead
+DISALLOW: /tmp
+`
+	rules := parseRobots(strings.NewReader(robots))
+
+	tests := []struct {
+		name  string
+		agent string
+		path  string
+		want  bool
+	}{
+		{"star group disallows", "somebot", "/private/page", false},
+		{"star group longer allow wins", "somebot", "/private/public/page", true},
+		{"star group anchored pattern", "somebot", "/files/a.pdf", false},
+		{"star group anchored pattern with query", "somebot", "/files/a.pdf?x=1", true},
+		{"star group unmatched path", "somebot", "/", true},
+		{"named group replaces star group", "dailyread", "/private/page", true},
+		{"named group disallows", "dailyread", "/drafts/new", false},
+		{"named group longer allow wins", "dailyread", "/drafts/published/1", true},
+		{"shared group", "otherbot", "/drafts/new", false},
+		{"groups for one agent are merged", "dailyread", "/tmp/x", false},
+		{"disallow everything", "blockedbot", "/", false},
+		{"empty disallow allows everything", "emptybot", "/private/page", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rules.allowed(tt.agent, tt.path); got != tt.want {
+				t.Errorf("allowed(%q, %q) = %v, want %v", tt.agent, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRobotsAllowTiesWin(t *testing.T) {
+	rules := parseRobots(strings.NewReader("User-agent: *\nDisallow: /page\nAllow: /page\n"))
+	if !rules.allowed("somebot", "/page") {
+		t.Error("an allow rule as long as the disallow rule should win")
+	}
+}
+
+func TestRobotsDisallowAll(t *testing.T) {
+	rules := &robotsRules{disallowAll: true}
+	if rules.allowed("dailyread", "/") {
+		t.Error("disallowAll allowed a path")
+	}
+}
+
+func TestProductToken(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		want      string
+	}{
+		{DefaultUserAgent, "dailyread"},
+		{"Mozilla/5.0 (X11; Linux x86_64)", "mozilla"},
+		{"MyBot crawler", "mybot"},
+		{"plain", "plain"},
+	}
+	for _, tt := range tests {
+		if got := productToken(tt.userAgent); got != tt.want {
+			t.Errorf("productToken(%q) = %q, want %q", tt.userAgent, got, tt.want)
+		}
+	}
+}
+
+func TestFetchRobotsStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		allowed bool
+	}{
+		{"rules apply", http.StatusOK, "User-agent: *\nDisallow: /feed\n", false},
+		{"missing file allows everything", http.StatusNotFound, "", true},
+		{"forbidden file allows everything", http.StatusForbidden, "", true},
+		{"server error disallows everything", http.StatusServiceUnavailable, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			f := New(Options{HostInterval: -1})
+			target, _ := url.Parse(srv.URL + "/feed")
+			got, err := f.robotsAllowed(context.Background(), target, DefaultUserAgent)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.allowed {
+				t.Errorf("robotsAllowed = %v, want %v", got, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestRobotsCached(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			requests.Add(1)
+			w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	f := New(Options{HostInterval: -1})
+	for _, path := range []string{"/a", "/b", "/c"} {
+		resp, err := f.Get(context.Background(), Request{URL: srv.URL + path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("robots.txt fetched %d times, want 1", n)
+	}
+
+	_, err := f.Get(context.Background(), Request{URL: srv.URL + "/private/page"})
+	if _, ok := err.(*RobotsDisallowedError); !ok {
+		t.Errorf("Get of a disallowed URL = %v, want *RobotsDisallowedError", err)
+	}
+}
@@ -0,0 +1,213 @@
+// Package fulltext downloads the articles behind posts of webpages in full
+// text mode and stores their extracted content.
+package fulltext
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cyberkillua/dailyread/internal/content"
+	"github.com/cyberkillua/dailyread/internal/database"
+	"github.com/cyberkillua/dailyread/internal/fetcher"
+	"github.com/cyberkillua/dailyread/internal/metrics"
+)
+
+// Values stored in post_contents.status.
+const (
+	StatusPending  = "pending"
+	StatusFetching = "fetching"
+	StatusDone     = "done"
+	StatusFailed   = "failed"
+)
+
+// retryDelay is the wait before a failed post is tried again, multiplied
+// by the number of attempts so far.
+const retryDelay = 10 * time.Minute
+
+const pageAccept = "text/html, application/xhtml+xml;q=0.9, */*;q=0.1"
+
+// errNotHTML reports an article URL that does not serve a web page, such as
+// a podcast episode or a PDF.
+var errNotHTML = errors.New("article is not an HTML page")
+
+// Worker claims queued posts, fetches their pages and stores the extracted
+// content. Several workers may share the queue; a claimed post is leased so
+// that a worker which dies mid-fetch does not hold it forever.
+type Worker struct {
+	DB      *database.Queries
+	Fetcher *fetcher.Fetcher
+	// Interval is the time between checks for queued posts.
+	Interval time.Duration
+	// Concurrency is the number of articles fetched in parallel.
+	Concurrency int
+	// LeaseDuration is how long a claimed post stays reserved.
+	LeaseDuration time.Duration
+	// MaxAttempts is how often a post is tried before it is marked failed.
+	MaxAttempts int32
+}
+
+func New(db *database.Queries, f *fetcher.Fetcher, concurrency int, interval time.Duration) *Worker {
+	return &Worker{
+		DB:            db,
+		Fetcher:       f,
+		Interval:      interval,
+		Concurrency:   concurrency,
+		LeaseDuration: 5 * time.Minute,
+		MaxAttempts:   3,
+	}
+}
+
+// Start drains the queue immediately and then every Interval until ctx is
+// cancelled.
+func (w *Worker) Start(ctx context.Context) {
+	slog.Info("Starting full text worker",
+		"concurrency", w.Concurrency,
+		"interval", w.Interval,
+	)
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.RunOnce(ctx); err != nil {
+			slog.Error("Full text run failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce processes queued posts, Concurrency at a time, until the queue
+// is empty, and returns how many were processed.
+func (w *Worker) RunOnce(ctx context.Context) (int, error) {
+	processed := 0
+	for ctx.Err() == nil {
+		now := time.Now().UTC()
+		claimed, err := w.DB.ClaimPostContents(ctx, database.ClaimPostContentsParams{
+			LeaseExpiresAt: now.Add(w.LeaseDuration),
+			Now:            now,
+			BatchSize:      int32(w.Concurrency),
+		})
+		if err != nil {
+			return processed, fmt.Errorf("claiming posts: %w", err)
+		}
+
+		wg := &sync.WaitGroup{}
+		for _, post := range claimed {
+			wg.Add(1)
+
+			go func(post database.ClaimPostContentsRow) {
+				defer wg.Done()
+				w.process(ctx, post)
+			}(post)
+		}
+		wg.Wait()
+
+		processed += len(claimed)
+		if len(claimed) < w.Concurrency {
+			break
+		}
+	}
+	return processed, nil
+}
+
+func (w *Worker) process(ctx context.Context, post database.ClaimPostContentsRow) {
+	logger := slog.With("post_id", post.PostID, "url", post.Url)
+	start := time.Now()
+
+	article, err := w.extract(ctx, post)
+	if err != nil {
+		now := time.Now().UTC()
+		status := StatusPending
+		outcome := metrics.ContentRetry
+		retryAt := sql.NullTime{Time: now.Add(retryDelay * time.Duration(post.Attempts)), Valid: true}
+
+		// Waiting out a host's Retry-After is not the article's fault, so
+		// the attempt taken by the claim is given back.
+		refund := false
+		var backoff *fetcher.HostBackoffError
+		switch {
+		case errors.As(err, &backoff):
+			retryAt.Time = backoff.Until.UTC()
+			refund = true
+		case permanent(err) || post.Attempts >= w.MaxAttempts:
+			status = StatusFailed
+			outcome = metrics.ContentFailed
+			retryAt = sql.NullTime{}
+		}
+		metrics.ContentExtractions.WithLabelValues(outcome).Inc()
+		logger.Warn("Error extracting full text", "error", err, "attempt", post.Attempts, "status", status)
+
+		err = w.DB.RecordPostContentFailure(ctx, database.RecordPostContentFailureParams{
+			Status:        status,
+			Error:         err.Error(),
+			RefundAttempt: refund,
+			RetryAt:       retryAt,
+			Now:           now,
+			PostID:        post.PostID,
+		})
+		if err != nil {
+			logger.Error("Error recording failed extraction", "error", err)
+		}
+		return
+	}
+
+	err = w.DB.StorePostContent(ctx, database.StorePostContentParams{
+		Content:     article.HTML,
+		ContentText: article.Text,
+		WordCount:   int32(article.WordCount),
+		FetchedAt:   time.Now().UTC(),
+		PostID:      post.PostID,
+	})
+	if err != nil {
+		logger.Error("Error storing full text", "error", err)
+		return
+	}
+
+	metrics.ContentExtractions.WithLabelValues(metrics.ContentDone).Inc()
+	logger.Debug("Extracted full text", "words", article.WordCount, "duration", time.Since(start))
+}
+
+func (w *Worker) extract(ctx context.Context, post database.ClaimPostContentsRow) (content.Article, error) {
+	resp, err := w.Fetcher.Get(ctx, fetcher.Request{
+		URL:       post.Url,
+		Accept:    pageAccept,
+		UserAgent: post.UserAgent.String,
+	})
+	if err != nil {
+		return content.Article{}, err
+	}
+	defer resp.Body.Close()
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType != "" {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+			return content.Article{}, fmt.Errorf("%w: %s", errNotHTML, mediaType)
+		}
+	}
+
+	return content.Extract(resp.Body, contentType, resp.URL)
+}
+
+// permanent reports whether retrying err is pointless.
+func permanent(err error) bool {
+	var statusErr *fetcher.HTTPStatusError
+	if errors.As(err, &statusErr) {
+		code := statusErr.StatusCode
+		return code >= 400 && code <= 499 && code != http.StatusRequestTimeout && code != http.StatusTooManyRequests
+	}
+	var blocked *fetcher.RobotsDisallowedError
+	return errors.As(err, &blocked) || errors.Is(err, content.ErrNoContent) || errors.Is(err, errNotHTML)
+}
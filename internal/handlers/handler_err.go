@@ -1,11 +1,20 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/cyberkillua/dailyread/internal/utils"
 )
 
 func HandlerErr(w http.ResponseWriter, r *http.Request) {
-	utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+	utils.RespondWithError(w, r, utils.ErrInternal(errors.New("test error endpoint")))
+}
+
+func HandlerNotFound(w http.ResponseWriter, r *http.Request) {
+	utils.RespondWithError(w, r, utils.ErrNotFound("No route matches "+r.URL.Path))
+}
+
+func HandlerMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	utils.RespondWithError(w, r, utils.NewAPIError(http.StatusMethodNotAllowed, utils.CodeMethodNotAllowed, r.Method+" is not allowed on "+r.URL.Path))
 }
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+
+	"github.com/cyberkillua/dailyread/internal/database"
+	"github.com/cyberkillua/dailyread/internal/models"
+	"github.com/cyberkillua/dailyread/internal/tagging"
+	"github.com/cyberkillua/dailyread/internal/utils"
+)
+
+const feedItemLimit = 50
+
+func (apiConfig *APIConfig) GetAllFeed(w http.ResponseWriter, r *http.Request) {
+	format, ok := feedFormat(chi.URLParam(r, "format"))
+	if !ok {
+		utils.RespondWithError(w, r, utils.ErrNotFound("Unknown feed format"))
+		return
+	}
+
+	posts, err := apiConfig.DB.GetFeedPosts(r.Context(), feedItemLimit)
+	if err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Posts"))
+		return
+	}
+
+	apiConfig.respondWithFeed(w, r, format, utils.FeedMeta{
+		Title:       "dailyRead",
+		Description: "Latest posts from every source followed on dailyRead",
+	}, models.DatabasePostsToPosts(posts))
+}
+
+func (apiConfig *APIConfig) GetWebpageFeed(w http.ResponseWriter, r *http.Request) {
+	format, ok := feedFormat(chi.URLParam(r, "format"))
+	if !ok {
+		utils.RespondWithError(w, r, utils.ErrNotFound("Unknown feed format"))
+		return
+	}
+
+	webpageID, err := uuid.Parse(chi.URLParam(r, "webpageID"))
+	if err != nil {
+		utils.RespondWithError(w, r, utils.ErrBadRequest(utils.CodeInvalidID, "Invalid webpage id"))
+		return
+	}
+
+	webpage, err := apiConfig.DB.GetWebpageByID(r.Context(), webpageID)
+	if err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Webpage"))
+		return
+	}
+
+	posts, err := apiConfig.DB.GetFeedPostsByWebpage(r.Context(), database.GetFeedPostsByWebpageParams{
+		WebpageID: uuid.NullUUID{UUID: webpage.ID, Valid: true},
+		Limit:     feedItemLimit,
+	})
+	if err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Posts"))
+		return
+	}
+
+	apiConfig.respondWithFeed(w, r, format, utils.FeedMeta{
+		Title:       webpage.Name,
+		Description: fmt.Sprintf("Latest posts from %s on dailyRead", webpage.Name),
+		HomeURL:     webpageHomeURL(webpage),
+	}, models.DatabasePostsToPosts(posts))
+}
+
+// webpageHomeURL returns the website a webpage's feed belongs to: the
+// channel link stored by the last successful scrape, or else the origin of
+// the feed URL, since the feed URL itself is not a page to read.
+func webpageHomeURL(webpage database.Webpage) string {
+	if webpage.SiteUrl.Valid && webpage.SiteUrl.String != "" {
+		return webpage.SiteUrl.String
+	}
+	u, err := url.Parse(webpage.Url)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return (&url.URL{Scheme: u.Scheme, Host: u.Host}).String()
+}
+
+// GetTagFeed serves /feeds/tags/{tagfeed}. Tags may contain dots, as in
+// "node.js", so the format is the extension after the last one rather than
+// a route parameter of its own.
+func (apiConfig *APIConfig) GetTagFeed(w http.ResponseWriter, r *http.Request) {
+	segment, err := url.PathUnescape(chi.URLParam(r, "tagfeed"))
+	if err != nil {
+		utils.RespondWithError(w, r, utils.ErrNotFound("Unknown tag"))
+		return
+	}
+	ext := path.Ext(segment)
+	format, ok := feedFormat(strings.TrimPrefix(ext, "."))
+	if !ok {
+		utils.RespondWithError(w, r, utils.ErrNotFound("Unknown feed format"))
+		return
+	}
+
+	tag := tagging.Normalize(strings.TrimSuffix(segment, ext))
+	if tag == "" {
+		utils.RespondWithError(w, r, utils.ErrNotFound("Unknown tag"))
+		return
+	}
+	posts, err := apiConfig.DB.GetFeedPostsByTag(r.Context(), database.GetFeedPostsByTagParams{
+		Tag:   tag,
+		Limit: feedItemLimit,
+	})
+	if err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Posts"))
+		return
+	}
+
+	apiConfig.respondWithFeed(w, r, format, utils.FeedMeta{
+		Title:       fmt.Sprintf("dailyRead: %s", tag),
+		Description: fmt.Sprintf("Latest %s posts on dailyRead", tag),
+	}, models.DatabasePostsToPosts(posts))
+}
+
+// respondWithFeed renders posts and answers conditional requests with 304
+// when the client already holds the current version of the feed.
+func (apiConfig *APIConfig) respondWithFeed(w http.ResponseWriter, r *http.Request, format string, meta utils.FeedMeta, posts []models.Post) {
+	baseURL := apiConfig.baseURL(r)
+	meta.SelfURL = baseURL + r.URL.Path
+	if meta.HomeURL == "" {
+		meta.HomeURL = baseURL
+	}
+	for _, post := range posts {
+		if post.UpdatedAt.After(meta.Updated) {
+			meta.Updated = post.UpdatedAt
+		}
+	}
+	if meta.Updated.IsZero() {
+		meta.Updated = time.Now().UTC()
+	}
+
+	data, err := utils.RenderFeed(format, meta, posts)
+	if err != nil {
+		utils.RespondWithError(w, r, utils.ErrInternal(fmt.Errorf("rendering feed: %w", err)))
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	lastModified := meta.Updated.UTC().Truncate(time.Second)
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	if notModified(r, etag, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", utils.FeedContentType(format))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+func (apiConfig *APIConfig) baseURL(r *http.Request) string {
+	if apiConfig.PublicURL != "" {
+		return apiConfig.PublicURL
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
+
+func feedFormat(ext string) (string, bool) {
+	switch strings.ToLower(ext) {
+	case "xml", "rss":
+		return utils.FeedFormatRSS, true
+	case "atom":
+		return utils.FeedFormatAtom, true
+	case "json":
+		return utils.FeedFormatJSON, true
+	default:
+		return "", false
+	}
+}
+
+// notModified evaluates If-None-Match before If-Modified-Since as required
+// by RFC 9110.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		t, err := http.ParseTime(ims)
+		if err == nil && !lastModified.After(t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/cyberkillua/dailyread/internal/database"
+)
+
+func TestWebpageHomeURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		siteURL sql.NullString
+		want    string
+	}{
+		{"site url", "https://example.com/feed.xml", sql.NullString{String: "https://www.example.com/blog", Valid: true}, "https://www.example.com/blog"},
+		{"origin", "https://example.com/blog/feed.xml?format=rss", sql.NullString{}, "https://example.com"},
+		{"origin with port", "http://example.com:8080/rss", sql.NullString{}, "http://example.com:8080"},
+		{"empty site url", "https://example.com/rss", sql.NullString{Valid: true}, "https://example.com"},
+		{"unparseable", "not a url", sql.NullString{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := webpageHomeURL(database.Webpage{Url: tt.url, SiteUrl: tt.siteURL})
+			if got != tt.want {
+				t.Errorf("webpageHomeURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+
+	"github.com/cyberkillua/dailyread/internal/database"
+	"github.com/cyberkillua/dailyread/internal/models"
+	"github.com/cyberkillua/dailyread/internal/utils"
+)
+
+// folderParameters is the body accepted when creating or updating a folder.
+type folderParameters struct {
+	Name string `json:"name"`
+	// ParentID nests the folder inside another one; null keeps it at the
+	// top level.
+	ParentID *uuid.UUID `json:"parent_id"`
+}
+
+// ListFolders returns every folder with its path and unread count.
+func (apiConfig *APIConfig) ListFolders(w http.ResponseWriter, r *http.Request) {
+	folders, err := apiConfig.DB.ListFolders(r.Context())
+	if err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Folders"))
+		return
+	}
+	counts, err := apiConfig.DB.GetFolderUnreadCounts(r.Context())
+	if err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Folders"))
+		return
+	}
+
+	byID := make(map[uuid.UUID]database.Folder, len(folders))
+	for _, folder := range folders {
+		byID[folder.ID] = folder
+	}
+	unread := make(map[uuid.UUID]int64, len(counts))
+	for _, count := range counts {
+		unread[count.FolderID] = count.UnreadCount
+	}
+
+	result := make([]models.Folder, 0, len(folders))
+	for _, dbFolder := range folders {
+		folder := models.DatabaseFolderToFolder(dbFolder)
+		folder.Path = utils.FolderPath(byID, dbFolder.ID)
+		folder.UnreadCount = unread[dbFolder.ID]
+		result = append(result, folder)
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, result)
+}
+
+func (apiConfig *APIConfig) CreateFolder(w http.ResponseWriter, r *http.Request) {
+	params, ok := decodeFolder(w, r)
+	if !ok {
+		return
+	}
+	if params.ParentID != nil && !checkFolderParent(w, r, apiConfig.DB, uuid.Nil, *params.ParentID) {
+		return
+	}
+
+	now := time.Now().UTC()
+	folder, err := apiConfig.DB.CreateFolder(r.Context(), database.CreateFolderParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Name:      params.Name,
+		ParentID:  nullUUID(params.ParentID),
+	})
+	if err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Folder"))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, models.DatabaseFolderToFolder(folder))
+}
+
+// UpdateFolder renames a folder or moves it under another parent. The
+// folder tree is locked while the move is checked and made, so concurrent
+// moves cannot create a cycle.
+func (apiConfig *APIConfig) UpdateFolder(w http.ResponseWriter, r *http.Request) {
+	folderID, err := uuid.Parse(chi.URLParam(r, "folderID"))
+	if err != nil {
+		utils.RespondWithError(w, r, utils.ErrBadRequest(utils.CodeInvalidID, "Invalid folder id"))
+		return
+	}
+
+	params, ok := decodeFolder(w, r)
+	if !ok {
+		return
+	}
+
+	tx, err := apiConfig.Conn.BeginTx(r.Context(), nil)
+	if err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Folder"))
+		return
+	}
+	defer tx.Rollback()
+	qtx := apiConfig.DB.WithTx(tx)
+
+	if err := qtx.LockFolders(r.Context()); err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Folder"))
+		return
+	}
+	if params.ParentID != nil && !checkFolderParent(w, r, qtx, folderID, *params.ParentID) {
+		return
+	}
+
+	folder, err := qtx.UpdateFolder(r.Context(), database.UpdateFolderParams{
+		ID:        folderID,
+		Name:      params.Name,
+		ParentID:  nullUUID(params.ParentID),
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Folder"))
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Folder"))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, models.DatabaseFolderToFolder(folder))
+}
+
+// decodeFolder reads and validates a folder body.
+func decodeFolder(w http.ResponseWriter, r *http.Request) (folderParameters, bool) {
+	params := folderParameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		utils.RespondWithError(w, r, utils.ErrBadRequest(utils.CodeInvalidBody, "Invalid request body"))
+		return params, false
+	}
+	params.Name = strings.TrimSpace(params.Name)
+
+	if params.Name == "" {
+		utils.RespondWithError(w, r, utils.ErrValidation(utils.FieldError{Field: "name", Code: "required", Message: "name is required"}))
+		return params, false
+	}
+	return params, true
+}
+
+// checkFolderParent reports whether parentID names a folder that folderID,
+// or uuid.Nil for a new folder, can be placed in; it responds when not.
+func checkFolderParent(w http.ResponseWriter, r *http.Request, db *database.Queries, folderID, parentID uuid.UUID) bool {
+	if _, err := db.GetFolderByID(r.Context(), parentID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.RespondWithError(w, r, utils.ErrValidation(utils.FieldError{Field: "parent_id", Code: "not_found", Message: "parent_id does not name a folder"}))
+		} else {
+			utils.RespondWithError(w, r, utils.DatabaseError(err, "Folder"))
+		}
+		return false
+	}
+	if folderID == uuid.Nil {
+		return true
+	}
+
+	cycle, err := db.IsFolderAncestor(r.Context(), database.IsFolderAncestorParams{
+		FolderID:   parentID,
+		AncestorID: folderID,
+	})
+	if err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Folder"))
+		return false
+	}
+	if cycle {
+		utils.RespondWithError(w, r, utils.ErrValidation(utils.FieldError{Field: "parent_id", Code: "cycle", Message: "a folder cannot be moved into itself or a folder below it"}))
+		return false
+	}
+	return true
+}
+
+// DeleteFolder removes a folder and the folders below it. The webpages in
+// them are kept.
+func (apiConfig *APIConfig) DeleteFolder(w http.ResponseWriter, r *http.Request) {
+	folderID, err := uuid.Parse(chi.URLParam(r, "folderID"))
+	if err != nil {
+		utils.RespondWithError(w, r, utils.ErrBadRequest(utils.CodeInvalidID, "Invalid folder id"))
+		return
+	}
+
+	deleted, err := apiConfig.DB.DeleteFolder(r.Context(), folderID)
+	if err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Folder"))
+		return
+	}
+	if deleted == 0 {
+		utils.RespondWithError(w, r, utils.ErrNotFound("Folder not found"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (apiConfig *APIConfig) AddWebpageToFolder(w http.ResponseWriter, r *http.Request) {
+	folderID, webpageID, ok := folderWebpageIDs(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := apiConfig.DB.GetFolderByID(r.Context(), folderID); err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Folder"))
+		return
+	}
+	if _, err := apiConfig.DB.GetWebpageByID(r.Context(), webpageID); err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Webpage"))
+		return
+	}
+
+	err := apiConfig.DB.AddWebpageToFolder(r.Context(), database.AddWebpageToFolderParams{
+		WebpageID: webpageID,
+		FolderID:  folderID,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Folder"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (apiConfig *APIConfig) RemoveWebpageFromFolder(w http.ResponseWriter, r *http.Request) {
+	folderID, webpageID, ok := folderWebpageIDs(w, r)
+	if !ok {
+		return
+	}
+
+	removed, err := apiConfig.DB.RemoveWebpageFromFolder(r.Context(), database.RemoveWebpageFromFolderParams{
+		WebpageID: webpageID,
+		FolderID:  folderID,
+	})
+	if err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Folder"))
+		return
+	}
+	if removed == 0 {
+		utils.RespondWithError(w, r, utils.ErrNotFound("Webpage is not in this folder"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func folderWebpageIDs(w http.ResponseWriter, r *http.Request) (folderID, webpageID uuid.UUID, ok bool) {
+	folderID, err := uuid.Parse(chi.URLParam(r, "folderID"))
+	if err != nil {
+		utils.RespondWithError(w, r, utils.ErrBadRequest(utils.CodeInvalidID, "Invalid folder id"))
+		return folderID, webpageID, false
+	}
+	webpageID, err = uuid.Parse(chi.URLParam(r, "webpageID"))
+	if err != nil {
+		utils.RespondWithError(w, r, utils.ErrBadRequest(utils.CodeInvalidID, "Invalid webpage id"))
+		return folderID, webpageID, false
+	}
+	return folderID, webpageID, true
+}
+
+// folderFilter reads the optional ?folder= query parameter. It reports
+// false after responding when the parameter is present but invalid.
+func (apiConfig *APIConfig) folderFilter(w http.ResponseWriter, r *http.Request) (uuid.NullUUID, bool) {
+	raw := r.URL.Query().Get("folder")
+	if raw == "" {
+		return uuid.NullUUID{}, true
+	}
+	folderID, err := uuid.Parse(raw)
+	if err != nil {
+		utils.RespondWithError(w, r, utils.ErrBadRequest(utils.CodeInvalidID, "Invalid folder id"))
+		return uuid.NullUUID{}, false
+	}
+	if _, err := apiConfig.DB.GetFolderByID(r.Context(), folderID); err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Folder"))
+		return uuid.NullUUID{}, false
+	}
+	return uuid.NullUUID{UUID: folderID, Valid: true}, true
+}
+
+// ExportOPML returns every webpage as an OPML document nested by folder.
+func (apiConfig *APIConfig) ExportOPML(w http.ResponseWriter, r *http.Request) {
+	webpages, err := apiConfig.DB.ListWebpages(r.Context())
+	if err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Webpages"))
+		return
+	}
+	folders, err := apiConfig.DB.ListFolders(r.Context())
+	if err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Folders"))
+		return
+	}
+	memberships, err := apiConfig.DB.ListWebpageFolders(r.Context())
+	if err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Folders"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/x-opml; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="dailyread.opml"`)
+	w.WriteHeader(http.StatusOK)
+	utils.WriteOPML(w, utils.BuildOPML("dailyRead subscriptions", webpages, folders, memberships))
+}
+
+func nullUUID(id *uuid.UUID) uuid.NullUUID {
+	if id == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: *id, Valid: true}
+}
@@ -1,19 +1,304 @@
 package handlers
 
 import (
-	"fmt"
+	"context"
+	"database/sql"
+	"errors"
 	"net/http"
 
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+
+	"github.com/cyberkillua/dailyread/internal/database"
 	"github.com/cyberkillua/dailyread/internal/models"
+	"github.com/cyberkillua/dailyread/internal/tagging"
 	"github.com/cyberkillua/dailyread/internal/utils"
 )
 
+// Orders accepted by the ?sort= parameter of the posts listing. Trending
+// posts are ranked by the score the trending scorer keeps up to date.
+const (
+	sortLatest   = "latest"
+	sortTrending = "trending"
+)
+
+// collapseClusters is the ?collapse= value that lists each story once.
+const collapseClusters = "clusters"
+
 func (apiConfig *APIConfig) GetPost(w http.ResponseWriter, r *http.Request) {
-	posts, err := apiConfig.DB.GetPosts(r.Context())
+	w.Header().Add("Vary", "Accept")
+
+	format, ok := utils.NegotiateFormat(r, utils.FormatJSON, utils.FormatNDJSON, utils.FormatCSV, utils.FormatAtom)
+	if !ok {
+		utils.RespondWithError(w, r, utils.NewAPIError(http.StatusNotAcceptable, utils.CodeNotAcceptable, "Supported formats are json, ndjson, csv and atom"))
+		return
+	}
+
+	sort := r.URL.Query().Get("sort")
+	if sort != "" && sort != sortLatest && sort != sortTrending {
+		utils.RespondWithError(w, r, utils.ErrValidation(utils.FieldError{Field: "sort", Code: "invalid", Message: "sort must be latest or trending"}))
+		return
+	}
+
+	collapse := r.URL.Query().Get("collapse")
+	if collapse != "" && collapse != collapseClusters {
+		utils.RespondWithError(w, r, utils.ErrValidation(utils.FieldError{Field: "collapse", Code: "invalid", Message: "collapse must be clusters"}))
+		return
+	}
+
+	tag := sql.NullString{}
+	if raw := r.URL.Query().Get("tag"); raw != "" {
+		tag.String = tagging.Normalize(raw)
+		tag.Valid = true
+		if tag.String == "" {
+			utils.RespondWithError(w, r, utils.ErrValidation(utils.FieldError{Field: "tag", Code: "invalid", Message: "tag must contain a letter or digit"}))
+			return
+		}
+	}
+
+	folderID, ok := apiConfig.folderFilter(w, r)
+	if !ok {
+		return
+	}
+
+	var posts []database.Post
+	var err error
+	switch {
+	case sort == sortTrending:
+		posts, err = apiConfig.DB.GetTrendingPosts(r.Context(), database.GetTrendingPostsParams{
+			FolderID: folderID,
+			Tag:      tag,
+		})
+	case tag.Valid:
+		posts, err = apiConfig.DB.GetPostsByTag(r.Context(), database.GetPostsByTagParams{
+			FolderID: folderID,
+			Tag:      tag.String,
+		})
+	case folderID.Valid:
+		posts, err = apiConfig.DB.GetPostsInFolder(r.Context(), folderID.UUID)
+	default:
+		posts, err = apiConfig.DB.GetPosts(r.Context())
+	}
+	if err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Posts"))
+		return
+	}
+
+	var enc utils.ResponseEncoder
+	if format == utils.FormatAtom {
+		baseURL := apiConfig.baseURL(r)
+		enc = utils.AtomEncoder{Meta: utils.FeedMeta{
+			Title:   "dailyRead",
+			HomeURL: baseURL,
+			SelfURL: baseURL + r.URL.RequestURI(),
+		}}
+	} else {
+		enc, _ = utils.NewEncoder(format)
+	}
+
+	if collapse != collapseClusters {
+		utils.RespondWithEncoder(w, http.StatusOK, enc, models.DatabasePostsToPosts(posts))
+		return
+	}
+
+	clustered, err := apiConfig.collapseClusters(r.Context(), posts)
+	if err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Posts"))
+		return
+	}
+	// CSV rows and Atom entries have no room for siblings, so those formats
+	// list only the representatives.
+	if format == utils.FormatCSV || format == utils.FormatAtom {
+		representatives := make([]models.Post, len(clustered))
+		for i, c := range clustered {
+			representatives[i] = c.Post
+		}
+		utils.RespondWithEncoder(w, http.StatusOK, enc, representatives)
+		return
+	}
+	utils.RespondWithEncoder(w, http.StatusOK, enc, clustered)
+}
+
+// collapseClusters keeps the first post listed from each cluster and
+// attaches the other posts of the cluster to it, in publication order.
+func (apiConfig *APIConfig) collapseClusters(ctx context.Context, posts []database.Post) ([]models.ClusteredPost, error) {
+	var clusterIDs []uuid.UUID
+	var representatives []database.Post
+	seen := make(map[uuid.UUID]bool)
+	for _, post := range posts {
+		// A post that has not been clustered yet stands alone.
+		key := post.ID
+		if post.ClusterID.Valid {
+			key = post.ClusterID.UUID
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		representatives = append(representatives, post)
+		if post.ClusterID.Valid {
+			clusterIDs = append(clusterIDs, key)
+		}
+	}
+
+	siblings := make(map[uuid.UUID][]models.Post)
+	if len(clusterIDs) > 0 {
+		members, err := apiConfig.DB.GetPostsInClusters(ctx, clusterIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range members {
+			siblings[member.ClusterID.UUID] = append(siblings[member.ClusterID.UUID], models.DatabasePostToPost(member))
+		}
+	}
+
+	clustered := make([]models.ClusteredPost, 0, len(representatives))
+	for _, post := range representatives {
+		c := models.ClusteredPost{Post: models.DatabasePostToPost(post), Siblings: []models.Post{}}
+		for _, sibling := range siblings[post.ClusterID.UUID] {
+			if post.ClusterID.Valid && sibling.ID != post.ID {
+				c.Siblings = append(c.Siblings, sibling)
+			}
+		}
+		clustered = append(clustered, c)
+	}
+	return clustered, nil
+}
+
+// GetPostByID returns a single post with its metadata, source webpage,
+// extracted full text and the neighbouring posts from the same webpage.
+func (apiConfig *APIConfig) GetPostByID(w http.ResponseWriter, r *http.Request) {
+	postID, err := uuid.Parse(chi.URLParam(r, "postID"))
+	if err != nil {
+		utils.RespondWithError(w, r, utils.ErrBadRequest(utils.CodeInvalidID, "Invalid post id"))
+		return
+	}
+
+	post, err := apiConfig.DB.GetPostByID(r.Context(), postID)
+	if err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Post"))
+		return
+	}
+	detail := models.DatabasePostToPostDetail(post)
+
+	tags, err := apiConfig.DB.GetPostTags(r.Context(), postID)
+	if err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Post tags"))
+		return
+	}
+	detail.Tags = models.DatabasePostTagsToTags(tags)
+
+	postContent, err := apiConfig.DB.GetPostContent(r.Context(), postID)
+	switch {
+	case err == nil:
+		c := models.DatabasePostContentToPostContent(postContent)
+		detail.Content = &c
+	case !errors.Is(err, sql.ErrNoRows):
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Post content"))
+		return
+	}
+
+	if post.WebpageID.Valid {
+		webpage, err := apiConfig.DB.GetWebpageByID(r.Context(), post.WebpageID.UUID)
+		switch {
+		case err == nil:
+			wp := models.DatabaseWebpageToWebpage(webpage)
+			detail.Webpage = &wp
+		case !errors.Is(err, sql.ErrNoRows):
+			utils.RespondWithError(w, r, utils.DatabaseError(err, "Webpage"))
+			return
+		}
+
+		sortAt := post.CreatedAt
+		if post.PublishedAt.Valid {
+			sortAt = post.PublishedAt.Time
+		}
+		detail.PreviousID, err = neighbourID(apiConfig.DB.GetPreviousPostID(r.Context(), database.GetPreviousPostIDParams{
+			WebpageID: post.WebpageID,
+			SortAt:    sortAt,
+			ID:        post.ID,
+		}))
+		if err != nil {
+			utils.RespondWithError(w, r, utils.DatabaseError(err, "Post"))
+			return
+		}
+		detail.NextID, err = neighbourID(apiConfig.DB.GetNextPostID(r.Context(), database.GetNextPostIDParams{
+			WebpageID: post.WebpageID,
+			SortAt:    sortAt,
+			ID:        post.ID,
+		}))
+		if err != nil {
+			utils.RespondWithError(w, r, utils.DatabaseError(err, "Post"))
+			return
+		}
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, detail)
+}
+
+// neighbourID turns the result of a neighbour lookup into an optional id.
+func neighbourID(id uuid.UUID, err error) (*uuid.UUID, error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+func (apiConfig *APIConfig) StarPost(w http.ResponseWriter, r *http.Request) {
+	apiConfig.setPostStarred(w, r, true)
+}
+
+func (apiConfig *APIConfig) UnstarPost(w http.ResponseWriter, r *http.Request) {
+	apiConfig.setPostStarred(w, r, false)
+}
+
+// setPostStarred toggles the flag that exempts a post from retention.
+func (apiConfig *APIConfig) setPostStarred(w http.ResponseWriter, r *http.Request, starred bool) {
+	postID, err := uuid.Parse(chi.URLParam(r, "postID"))
+	if err != nil {
+		utils.RespondWithError(w, r, utils.ErrBadRequest(utils.CodeInvalidID, "Invalid post id"))
+		return
+	}
+
+	post, err := apiConfig.DB.SetPostStarred(r.Context(), database.SetPostStarredParams{
+		ID:      postID,
+		Starred: starred,
+	})
+	if err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Post"))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, models.DatabasePostToPost(post))
+}
+
+func (apiConfig *APIConfig) MarkPostRead(w http.ResponseWriter, r *http.Request) {
+	apiConfig.setPostRead(w, r, true)
+}
+
+func (apiConfig *APIConfig) MarkPostUnread(w http.ResponseWriter, r *http.Request) {
+	apiConfig.setPostRead(w, r, false)
+}
+
+// setPostRead toggles the flag behind the unread counts.
+func (apiConfig *APIConfig) setPostRead(w http.ResponseWriter, r *http.Request, read bool) {
+	postID, err := uuid.Parse(chi.URLParam(r, "postID"))
+	if err != nil {
+		utils.RespondWithError(w, r, utils.ErrBadRequest(utils.CodeInvalidID, "Invalid post id"))
+		return
+	}
+
+	post, err := apiConfig.DB.SetPostRead(r.Context(), database.SetPostReadParams{
+		ID:   postID,
+		Read: read,
+	})
 	if err != nil {
-		utils.RespondWithError(w, http.StatusInternalServerError, fmt.Sprint("Error getting posts: %v", err))
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Post"))
 		return
 	}
 
-	utils.RespondWithJSON(w, http.StatusOK, models.DatabasePostsToPosts(posts))
+	utils.RespondWithJSON(w, http.StatusOK, models.DatabasePostToPost(post))
 }
@@ -3,9 +3,29 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/cyberkillua/dailyread/internal/health"
 	"github.com/cyberkillua/dailyread/internal/utils"
 )
 
 func HandlerReadiness(w http.ResponseWriter, r *http.Request) {
 	utils.RespondWithJSON(w, 200, struct{}{})
 }
+
+// HandlerLiveness only reports that the process is serving requests; it
+// deliberately ignores dependencies so an unavailable database does not
+// cause the process to be restarted.
+func HandlerLiveness(w http.ResponseWriter, r *http.Request) {
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"status": health.StatusOK})
+}
+
+func (apiConfig *APIConfig) HandlerReadyz(w http.ResponseWriter, r *http.Request) {
+	report := apiConfig.Health.Ready(r.Context())
+
+	code := http.StatusOK
+	if report.Status != health.StatusOK {
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	utils.RespondWithJSON(w, code, report)
+}
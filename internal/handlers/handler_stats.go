@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/cyberkillua/dailyread/internal/database"
+	"github.com/cyberkillua/dailyread/internal/models"
+	"github.com/cyberkillua/dailyread/internal/utils"
+)
+
+// GetStats returns post counts and fetch state for every webpage, plus
+// totals across all of them, for a reader's sidebar.
+func (apiConfig *APIConfig) GetStats(w http.ResponseWriter, r *http.Request) {
+	now := time.Now().UTC()
+	dayAgo := now.Add(-24 * time.Hour)
+	weekAgo := now.Add(-7 * 24 * time.Hour)
+
+	webpages, err := apiConfig.DB.GetWebpageStats(r.Context(), database.GetWebpageStatsParams{
+		DayAgo:  dayAgo,
+		WeekAgo: weekAgo,
+	})
+	if err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Stats"))
+		return
+	}
+	totals, err := apiConfig.DB.GetPostTotals(r.Context(), database.GetPostTotalsParams{
+		DayAgo:  dayAgo,
+		WeekAgo: weekAgo,
+	})
+	if err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Stats"))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, models.DatabaseStatsToStats(totals, webpages))
+}
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/cyberkillua/dailyread/internal/models"
+	"github.com/cyberkillua/dailyread/internal/utils"
+)
+
+// tagListLimit is the number of tags returned by the tag listing.
+const tagListLimit = 200
+
+// ListTags returns the most used tags with their post counts.
+func (apiConfig *APIConfig) ListTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := apiConfig.DB.ListTags(r.Context(), tagListLimit)
+	if err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Tags"))
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, models.DatabaseTagsToTags(tags))
+}
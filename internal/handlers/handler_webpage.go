@@ -1,20 +1,29 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/cyberkillua/dailyread/internal/database"
+	"github.com/cyberkillua/dailyread/internal/health"
 	"github.com/cyberkillua/dailyread/internal/models"
 	"github.com/cyberkillua/dailyread/internal/utils"
 )
 
 type APIConfig struct {
-	DB *database.Queries
+	// Conn is used for handlers that need a transaction; DB runs every
+	// other query.
+	Conn      *sql.DB
+	DB        *database.Queries
+	Health    *health.Checker
+	PublicURL string
 }
 
 func (apiConfig *APIConfig) CreateWebpage(w http.ResponseWriter, r *http.Request) {
@@ -22,13 +31,56 @@ func (apiConfig *APIConfig) CreateWebpage(w http.ResponseWriter, r *http.Request
 		Name string `json:"name"`
 		URL  string `json:"url"`
 		Type string `json:"type"`
+		// MaxItemAgeDays overrides the global ingest cutoff; 0 keeps items
+		// of any age.
+		MaxItemAgeDays *int32 `json:"max_item_age_days"`
+		// UserAgent overrides the User-Agent sent when fetching the feed.
+		UserAgent string `json:"user_agent"`
+		// FullText extracts the full article behind each new post.
+		FullText bool `json:"full_text"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
 	err := decoder.Decode(&params)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		utils.RespondWithError(w, r, utils.ErrBadRequest(utils.CodeInvalidBody, "Invalid request body"))
+		return
+	}
+
+	var fieldErrors []utils.FieldError
+	if strings.TrimSpace(params.Name) == "" {
+		fieldErrors = append(fieldErrors, utils.FieldError{Field: "name", Code: "required", Message: "name is required"})
+	}
+	if params.URL == "" {
+		fieldErrors = append(fieldErrors, utils.FieldError{Field: "url", Code: "required", Message: "url is required"})
+	} else if !utils.ValidFeedURL(params.URL) {
+		fieldErrors = append(fieldErrors, utils.FieldError{Field: "url", Code: "invalid_url", Message: "url must be an absolute http or https URL"})
+	}
+	maxItemAgeDays := sql.NullInt32{}
+	if params.MaxItemAgeDays != nil {
+		if *params.MaxItemAgeDays < 0 {
+			fieldErrors = append(fieldErrors, utils.FieldError{Field: "max_item_age_days", Code: "out_of_range", Message: "max_item_age_days must not be negative"})
+		}
+		maxItemAgeDays = sql.NullInt32{Int32: *params.MaxItemAgeDays, Valid: true}
+	}
+	if strings.ContainsAny(params.UserAgent, "\r\n") {
+		fieldErrors = append(fieldErrors, utils.FieldError{Field: "user_agent", Code: "invalid", Message: "user_agent must be a single line"})
+	}
+	if len(fieldErrors) > 0 {
+		utils.RespondWithError(w, r, utils.ErrValidation(fieldErrors...))
+		return
+	}
+
+	// A feed that has moved is still known by its old URL.
+	existing, err := apiConfig.DB.GetWebpageByURLOrAlias(r.Context(), params.URL)
+	if err == nil {
+		utils.RespondWithError(w, r, utils.NewAPIError(http.StatusConflict, utils.CodeConflict,
+			fmt.Sprintf("Webpage %s already uses this URL or has moved from it to %s", existing.ID, existing.Url)))
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Webpage"))
 		return
 	}
 
@@ -39,13 +91,57 @@ func (apiConfig *APIConfig) CreateWebpage(w http.ResponseWriter, r *http.Request
 		Name:      params.Name,
 		Url:       params.URL,
 		Type:      params.Type,
+
+		MaxItemAgeDays: maxItemAgeDays,
+		UserAgent:      sql.NullString{String: params.UserAgent, Valid: params.UserAgent != ""},
+		FullText:       params.FullText,
 	})
 
 	if err != nil {
-		utils.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Error creating webpage: %v", err))
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Webpage"))
 		return
 	}
 
 	utils.RespondWithJSON(w, http.StatusCreated, models.DatabaseWebpageToWebpage(webpage))
 
 }
+
+// ListWebpages returns every webpage, or with ?folder= only those in that
+// folder and the folders below it, each with the folders it is in.
+func (apiConfig *APIConfig) ListWebpages(w http.ResponseWriter, r *http.Request) {
+	folderID, ok := apiConfig.folderFilter(w, r)
+	if !ok {
+		return
+	}
+
+	var webpages []database.Webpage
+	var err error
+	if folderID.Valid {
+		webpages, err = apiConfig.DB.ListWebpagesInFolder(r.Context(), folderID.UUID)
+	} else {
+		webpages, err = apiConfig.DB.ListWebpages(r.Context())
+	}
+	if err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Webpages"))
+		return
+	}
+
+	memberships, err := apiConfig.DB.ListWebpageFolders(r.Context())
+	if err != nil {
+		utils.RespondWithError(w, r, utils.DatabaseError(err, "Folders"))
+		return
+	}
+	folderIDs := make(map[uuid.UUID][]uuid.UUID)
+	for _, m := range memberships {
+		folderIDs[m.WebpageID] = append(folderIDs[m.WebpageID], m.FolderID)
+	}
+
+	result := make([]models.Webpage, 0, len(webpages))
+	for _, dbWebpage := range webpages {
+		webpage := models.DatabaseWebpageToWebpage(dbWebpage)
+		webpage.FolderIDs = folderIDs[dbWebpage.ID]
+		result = append(result, webpage)
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,157 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cyberkillua/dailyread/sql/schema"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusDisabled = "disabled"
+)
+
+// Checker evaluates the components the API depends on.
+type Checker struct {
+	DB *sql.DB
+	// Scraper is nil when this process does not run the scraper.
+	Scraper *Heartbeat
+	// Timeout bounds each database check.
+	Timeout time.Duration
+	// MaxHeartbeatAge is how stale the scraper heartbeat may become before
+	// the scraper is reported as degraded.
+	MaxHeartbeatAge time.Duration
+	// ExpectedMigration is the schema version the binary was built for.
+	ExpectedMigration int64
+}
+
+// Report is the readiness breakdown returned to clients.
+type Report struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components"`
+}
+
+type Component struct {
+	Status  string                 `json:"status"`
+	Error   string                 `json:"error,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+func NewChecker(db *sql.DB, scraper *Heartbeat) *Checker {
+	return &Checker{
+		DB:                db,
+		Scraper:           scraper,
+		Timeout:           2 * time.Second,
+		MaxHeartbeatAge:   2 * time.Minute,
+		ExpectedMigration: schema.LatestVersion(),
+	}
+}
+
+// Ready runs every check. The report is degraded if any component is.
+func (c *Checker) Ready(ctx context.Context) Report {
+	report := Report{
+		Status: StatusOK,
+		Components: map[string]Component{
+			"database":   c.checkDatabase(ctx),
+			"migrations": c.checkMigrations(ctx),
+			"scraper":    c.checkScraper(),
+		},
+	}
+
+	for _, component := range report.Components {
+		if component.Status == StatusDegraded {
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+func (c *Checker) checkDatabase(ctx context.Context) Component {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := c.DB.PingContext(ctx); err != nil {
+		return Component{Status: StatusDegraded, Error: err.Error()}
+	}
+	return Component{
+		Status:  StatusOK,
+		Details: map[string]interface{}{"latency_ms": time.Since(start).Milliseconds()},
+	}
+}
+
+func (c *Checker) checkMigrations(ctx context.Context) Component {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	current, err := migrationVersion(ctx, c.DB)
+	details := map[string]interface{}{
+		"current":  current,
+		"expected": c.ExpectedMigration,
+	}
+	if err != nil {
+		return Component{Status: StatusDegraded, Error: err.Error(), Details: details}
+	}
+	if current < c.ExpectedMigration {
+		return Component{
+			Status:  StatusDegraded,
+			Error:   fmt.Sprintf("database schema is at version %d, expected %d", current, c.ExpectedMigration),
+			Details: details,
+		}
+	}
+	return Component{Status: StatusOK, Details: details}
+}
+
+func (c *Checker) checkScraper() Component {
+	if c.Scraper == nil {
+		return Component{Status: StatusDisabled}
+	}
+
+	status := c.Scraper.Status()
+	if !status.Enabled {
+		return Component{Status: StatusDegraded, Error: "scraper has not started"}
+	}
+
+	age := time.Since(status.LastBeat)
+	details := map[string]interface{}{
+		"heartbeat_age_seconds": int64(age.Seconds()),
+	}
+	if !status.LastSuccess.IsZero() {
+		details["last_successful_cycle"] = status.LastSuccess.UTC()
+	}
+	if status.LastError != "" {
+		details["last_error"] = status.LastError
+	}
+
+	if age > c.MaxHeartbeatAge {
+		return Component{
+			Status:  StatusDegraded,
+			Error:   fmt.Sprintf("no scraper heartbeat for %s", age.Round(time.Second)),
+			Details: details,
+		}
+	}
+	return Component{Status: StatusOK, Details: details}
+}
+
+// migrationVersion reads the current version from goose's bookkeeping table.
+// goose appends a row for every up and down migration, so the newest row per
+// version decides whether that version is applied.
+func migrationVersion(ctx context.Context, db *sql.DB) (int64, error) {
+	var version sql.NullInt64
+	err := db.QueryRowContext(ctx, `
+		SELECT MAX(version_id) FROM (
+			SELECT DISTINCT ON (version_id) version_id, is_applied
+			FROM goose_db_version
+			ORDER BY version_id, id DESC
+		) latest
+		WHERE is_applied
+	`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("reading migration version: %w", err)
+	}
+	return version.Int64, nil
+}
@@ -0,0 +1,76 @@
+package health
+
+import (
+	"sync"
+	"time"
+)
+
+// Heartbeat is updated by a background worker so readiness checks can tell
+// whether it is still alive and making progress. Updating a nil Heartbeat is
+// a no-op.
+type Heartbeat struct {
+	mu          sync.RWMutex
+	enabled     bool
+	lastBeat    time.Time
+	lastSuccess time.Time
+	lastError   string
+}
+
+// HeartbeatStatus is a point in time copy of a Heartbeat.
+type HeartbeatStatus struct {
+	Enabled     bool
+	LastBeat    time.Time
+	LastSuccess time.Time
+	LastError   string
+}
+
+func NewHeartbeat() *Heartbeat {
+	return &Heartbeat{}
+}
+
+// Beat records that the worker is alive.
+func (h *Heartbeat) Beat() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.enabled = true
+	h.lastBeat = time.Now()
+}
+
+// CycleSucceeded records a completed unit of work.
+func (h *Heartbeat) CycleSucceeded() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.enabled = true
+	h.lastBeat = time.Now()
+	h.lastSuccess = h.lastBeat
+	h.lastError = ""
+}
+
+// CycleFailed records a unit of work that could not be completed.
+func (h *Heartbeat) CycleFailed(err error) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.enabled = true
+	h.lastBeat = time.Now()
+	h.lastError = err.Error()
+}
+
+func (h *Heartbeat) Status() HeartbeatStatus {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return HeartbeatStatus{
+		Enabled:     h.enabled,
+		LastBeat:    h.lastBeat,
+		LastSuccess: h.lastSuccess,
+		LastError:   h.lastError,
+	}
+}
@@ -0,0 +1,131 @@
+package janitor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/cyberkillua/dailyread/internal/database"
+	"github.com/cyberkillua/dailyread/internal/metrics"
+)
+
+const (
+	ModeDelete  = "delete"
+	ModeArchive = "archive"
+)
+
+// lockID is the Postgres advisory lock that keeps two processes from
+// pruning at the same time.
+const lockID = 0x6461696c7901
+
+// Janitor periodically removes posts that are past the retention period.
+// Starred posts are always kept.
+type Janitor struct {
+	Conn *sql.DB
+	// RetentionDays is the age, in days, after which posts are removed.
+	RetentionDays int
+	// Mode is ModeDelete or ModeArchive.
+	Mode     string
+	Interval time.Duration
+	// BatchSize bounds the rows removed per statement so the janitor
+	// never holds locks on a large part of the table.
+	BatchSize int32
+}
+
+func New(conn *sql.DB, retentionDays int, mode string, interval time.Duration) *Janitor {
+	return &Janitor{
+		Conn:          conn,
+		RetentionDays: retentionDays,
+		Mode:          mode,
+		Interval:      interval,
+		BatchSize:     1000,
+	}
+}
+
+// Start runs the janitor immediately and then every Interval until ctx is
+// cancelled.
+func (j *Janitor) Start(ctx context.Context) {
+	slog.Info("Starting retention janitor",
+		"retention_days", j.RetentionDays,
+		"mode", j.Mode,
+		"interval", j.Interval,
+	)
+
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.RunOnce(ctx); err != nil {
+			slog.Error("Retention run failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce removes every expired post and returns how many were removed. It
+// returns without doing anything if another process holds the janitor lock.
+func (j *Janitor) RunOnce(ctx context.Context) (int64, error) {
+	conn, err := j.Conn.Conn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("acquiring connection: %w", err)
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", lockID).Scan(&locked); err != nil {
+		return 0, fmt.Errorf("acquiring janitor lock: %w", err)
+	}
+	if !locked {
+		slog.Debug("Retention run skipped, another process holds the lock")
+		return 0, nil
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID)
+
+	start := time.Now()
+	now := start.UTC()
+	cutoff := now.AddDate(0, 0, -j.RetentionDays)
+	db := database.New(conn)
+
+	var removed int64
+	for {
+		var n int64
+		if j.Mode == ModeArchive {
+			n, err = db.ArchiveExpiredPosts(ctx, database.ArchiveExpiredPostsParams{
+				Cutoff:     cutoff,
+				BatchSize:  j.BatchSize,
+				ArchivedAt: now,
+			})
+		} else {
+			n, err = db.DeleteExpiredPosts(ctx, database.DeleteExpiredPostsParams{
+				Cutoff:    cutoff,
+				BatchSize: j.BatchSize,
+			})
+		}
+		if err != nil {
+			return removed, fmt.Errorf("removing expired posts: %w", err)
+		}
+
+		removed += n
+		metrics.RetentionRemoved.WithLabelValues(j.Mode).Add(float64(n))
+		if n < int64(j.BatchSize) {
+			break
+		}
+	}
+
+	metrics.RetentionLastRun.SetToCurrentTime()
+	metrics.RetentionLastRemoved.Set(float64(removed))
+	slog.Info("Retention run finished",
+		"mode", j.Mode,
+		"cutoff", cutoff,
+		"removed", removed,
+		"duration", time.Since(start),
+	)
+	return removed, nil
+}
@@ -0,0 +1,29 @@
+package logging
+
+import (
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/cyberkillua/dailyread/internal/config"
+)
+
+// Setup builds the process wide logger from cfg and installs it as the
+// slog default, which also routes the standard log package through it.
+func Setup(cfg *config.Config) *slog.Logger {
+	logger := New(os.Stderr, cfg.LogFormat, cfg.LogLevel)
+	slog.SetDefault(logger)
+	return logger
+}
+
+func New(w io.Writer, format string, level slog.Level) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	if format == "json" {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
+	return slog.New(handler)
+}
@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "dailyread"
+
+// Registry holds every dailyRead metric. A dedicated registry keeps the
+// exposition independent of anything third party packages register globally.
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time spent handling HTTP requests, by method and route pattern.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	FeedFetches = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "feed_fetches_total",
+		Help:      "Feed fetch attempts, by webpage and outcome.",
+	}, []string{"webpage_id", "outcome"})
+
+	FeedFetchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "feed_fetch_duration_seconds",
+		Help:      "Time spent fetching and parsing a feed, by outcome.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30},
+	}, []string{"outcome"})
+
+	FeedLastFetchDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "feed_last_fetch_duration_seconds",
+		Help:      "Duration of the most recent fetch of each webpage.",
+	}, []string{"webpage_id"})
+
+	FeedItems = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "feed_items_total",
+		Help:      "Feed items seen by the scraper, by result (parsed, created, updated, duplicate, skipped_too_old, skipped_invalid, date_fallback).",
+	}, []string{"result"})
+
+	SchedulerLag = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "scheduler_lag_seconds",
+		Help:      "Delay between when the last scrape cycle was due and when it started.",
+	})
+
+	RetentionRemoved = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "retention_posts_removed_total",
+		Help:      "Posts removed by the retention janitor, by mode (delete or archive).",
+	}, []string{"mode"})
+
+	RetentionLastRun = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "retention_last_run_timestamp_seconds",
+		Help:      "Unix time of the last completed retention run.",
+	})
+
+	RetentionLastRemoved = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "retention_last_run_posts_removed",
+		Help:      "Posts removed by the last completed retention run.",
+	})
+
+	TrendingLastRun = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "trending_last_run_timestamp_seconds",
+		Help:      "Unix time of the last completed trending run.",
+	})
+
+	TrendingLastScored = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "trending_last_run_posts_scored",
+		Help:      "Posts scored by the last completed trending run.",
+	})
+
+	ContentExtractions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "content_extractions_total",
+		Help:      "Full text extraction attempts, by outcome (done, retry, failed).",
+	}, []string{"outcome"})
+
+	ScrapeCycleDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "scrape_cycle_duration_seconds",
+		Help:      "Time taken by a full scrape cycle.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+	})
+)
+
+// Feed fetch outcomes.
+const (
+	OutcomeSuccess    = "success"
+	OutcomeFetchError = "fetch_error"
+	OutcomeStoreError = "store_error"
+)
+
+// Feed item results.
+const (
+	ItemParsed         = "parsed"
+	ItemCreated        = "created"
+	ItemUpdated        = "updated"
+	ItemDuplicate      = "duplicate"
+	ItemSkippedTooOld  = "skipped_too_old"
+	ItemSkippedInvalid = "skipped_invalid"
+	// ItemDateFallback counts items whose dates could not be parsed and
+	// were stored with the time they were fetched instead.
+	ItemDateFallback = "date_fallback"
+)
+
+// Full text extraction outcomes.
+const (
+	ContentDone   = "done"
+	ContentRetry  = "retry"
+	ContentFailed = "failed"
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		FeedFetches,
+		FeedFetchDuration,
+		FeedLastFetchDuration,
+		FeedItems,
+		SchedulerLag,
+		ScrapeCycleDuration,
+		RetentionRemoved,
+		RetentionLastRun,
+		RetentionLastRemoved,
+		TrendingLastRun,
+		TrendingLastScored,
+		ContentExtractions,
+	)
+}
+
+// RegisterDBStats exports connection pool statistics for db.
+func RegisterDBStats(db *sql.DB) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, "postgres"))
+}
+
+// Handler serves the registry in the Prometheus text exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -0,0 +1,130 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log/slog"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+
+	"github.com/cyberkillua/dailyread/sql/schema"
+)
+
+// Commands lists the sub commands understood by Run.
+var Commands = []string{"up", "down", "status", "version"}
+
+// NewProvider returns a goose provider for the embedded migrations. Every
+// migration run holds a Postgres advisory lock, so replicas starting at the
+// same time apply migrations one after the other instead of racing.
+func NewProvider(db *sql.DB) (*goose.Provider, error) {
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, fmt.Errorf("creating migration lock: %w", err)
+	}
+
+	return goose.NewProvider(goose.DialectPostgres, db, schema.FS,
+		goose.WithSessionLocker(locker),
+	)
+}
+
+// Up applies every pending migration.
+func Up(ctx context.Context, db *sql.DB) error {
+	provider, err := NewProvider(db)
+	if err != nil {
+		return err
+	}
+
+	results, err := provider.Up(ctx)
+	for _, result := range results {
+		logResult(result)
+	}
+	if err != nil {
+		return fmt.Errorf("applying migrations: %w", err)
+	}
+
+	version, err := provider.GetDBVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("reading migration version: %w", err)
+	}
+	slog.Info("Database schema is up to date", "version", version, "applied", len(results))
+	return nil
+}
+
+// Run executes a migrate sub command and writes human readable output to out.
+func Run(ctx context.Context, db *sql.DB, command string, out io.Writer) error {
+	provider, err := NewProvider(db)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "up":
+		results, err := provider.Up(ctx)
+		printResults(out, results)
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			fmt.Fprintln(out, "no migrations to run")
+		}
+		return nil
+	case "down":
+		result, err := provider.Down(ctx)
+		if result != nil {
+			printResults(out, []*goose.MigrationResult{result})
+		}
+		return err
+	case "status":
+		statuses, err := provider.Status(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tSTATE\tAPPLIED AT\tFILE")
+		for _, status := range statuses {
+			appliedAt := "-"
+			if !status.AppliedAt.IsZero() {
+				appliedAt = status.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", status.Source.Version, status.State, appliedAt, status.Source.Path)
+		}
+		return tw.Flush()
+	case "version":
+		current, target, err := provider.GetVersions(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "current: %d\nlatest:  %d\n", current, target)
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected one of %v", command, Commands)
+	}
+}
+
+func printResults(out io.Writer, results []*goose.MigrationResult) {
+	for _, result := range results {
+		fmt.Fprintln(out, result)
+	}
+}
+
+func logResult(result *goose.MigrationResult) {
+	if result.Error != nil {
+		slog.Error("Migration failed",
+			"version", result.Source.Version,
+			"direction", result.Direction,
+			"error", result.Error,
+		)
+		return
+	}
+	slog.Info("Applied migration",
+		"version", result.Source.Version,
+		"direction", result.Direction,
+		"file", result.Source.Path,
+		"duration", result.Duration,
+	)
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/cyberkillua/dailyread/internal/database"
+	"github.com/google/uuid"
+)
+
+type Folder struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Name      string     `json:"name"`
+	ParentID  *uuid.UUID `json:"parent_id"`
+	// Path is the folder's name preceded by those of its parents.
+	Path []string `json:"path"`
+	// UnreadCount counts unread posts from webpages in this folder or any
+	// folder below it.
+	UnreadCount int64 `json:"unread_count"`
+}
+
+func DatabaseFolderToFolder(dbFolder database.Folder) Folder {
+	var parentID *uuid.UUID
+	if dbFolder.ParentID.Valid {
+		parentID = &dbFolder.ParentID.UUID
+	}
+
+	return Folder{
+		ID:        dbFolder.ID,
+		CreatedAt: dbFolder.CreatedAt,
+		UpdatedAt: dbFolder.UpdatedAt,
+		Name:      dbFolder.Name,
+		ParentID:  parentID,
+		Path:      []string{dbFolder.Name},
+	}
+}
@@ -1,33 +1,71 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
+	"github.com/cyberkillua/dailyread/internal/content"
 	"github.com/cyberkillua/dailyread/internal/database"
 	"github.com/google/uuid"
 )
 
 type Post struct {
-	ID          uuid.UUID `json:"id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Title       string    `json:"title"`
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Title     string    `json:"title"`
+	// DescriptionHTML is the sanitized HTML description; DescriptionText
+	// is the same description as plain text.
+	DescriptionHTML string `json:"description_html"`
+	DescriptionText string `json:"description_text"`
+	// Description repeats DescriptionHTML for clients written before the
+	// two were split.
 	Description string    `json:"description"`
 	Url         string    `json:"url"`
 	PublishedAt time.Time `json:"published_at"`
 	Postname    string    `json:"postname"`
+	WebpageID   uuid.UUID `json:"webpage_id"`
+	Starred     bool      `json:"starred"`
+	Read        bool      `json:"read"`
+	// Score ranks the post in the trending listing; it is zero for posts
+	// outside the trending window.
+	Score float64 `json:"score"`
+	// ClusterID is shared by posts from different sources covering the
+	// same story; it is null until the post has been clustered.
+	ClusterID *uuid.UUID `json:"cluster_id"`
 }
 
 func DatabasePostToPost(dbPost database.Post) Post {
+	descriptionHTML := dbPost.Description.String
+	descriptionText := dbPost.DescriptionText.String
+	// Posts ingested before descriptions were sanitized have no
+	// description_text and are cleaned when they are read.
+	if dbPost.Description.Valid && !dbPost.DescriptionText.Valid {
+		descriptionHTML = content.Sanitize(descriptionHTML, dbPost.Url)
+		descriptionText = content.PlainText(descriptionHTML)
+	}
+
+	var clusterID *uuid.UUID
+	if dbPost.ClusterID.Valid {
+		clusterID = &dbPost.ClusterID.UUID
+	}
+
 	return Post{
-		ID:          dbPost.ID,
-		CreatedAt:   dbPost.CreatedAt,
-		UpdatedAt:   dbPost.UpdatedAt,
-		Title:       dbPost.Title,
-		Description: dbPost.Description.String,
-		Url:         dbPost.Url,
-		PublishedAt: dbPost.PublishedAt.Time,
-		Postname:    dbPost.Postname.String,
+		ID:              dbPost.ID,
+		CreatedAt:       dbPost.CreatedAt,
+		UpdatedAt:       dbPost.UpdatedAt,
+		Title:           dbPost.Title,
+		DescriptionHTML: descriptionHTML,
+		DescriptionText: descriptionText,
+		Description:     descriptionHTML,
+		Url:             dbPost.Url,
+		PublishedAt:     dbPost.PublishedAt.Time,
+		Postname:        dbPost.Postname.String,
+		WebpageID:       dbPost.WebpageID.UUID,
+		Starred:         dbPost.Starred,
+		Read:            dbPost.Read,
+		Score:           dbPost.Score,
+		ClusterID:       clusterID,
 	}
 }
 
@@ -38,3 +76,79 @@ func DatabasePostsToPosts(dbPost []database.Post) []Post {
 	}
 	return posts
 }
+
+// ClusteredPost is the post chosen to represent a story together with the
+// other posts covering it.
+type ClusteredPost struct {
+	Post
+	Siblings []Post `json:"siblings"`
+}
+
+// PostDetail is a single post with everything stored about it. The
+// reader's state is the embedded post's Starred and Read; there are no user
+// accounts, so it is shared by everyone using the instance.
+type PostDetail struct {
+	Post
+	Author     string      `json:"author"`
+	Categories []string    `json:"categories"`
+	Enclosures []Enclosure `json:"enclosures"`
+	Tags       []PostTag   `json:"tags"`
+	// Webpage is the source the post came from, nil when the source has
+	// been removed.
+	Webpage *Webpage `json:"webpage"`
+	// Content is nil unless the post's webpage is in full text mode.
+	Content *PostContent `json:"content"`
+	// PreviousID and NextID are the posts from the same webpage published
+	// immediately before and after this one.
+	PreviousID *uuid.UUID `json:"previous_id"`
+	NextID     *uuid.UUID `json:"next_id"`
+}
+
+// Enclosure is a file attached to a post, such as a podcast episode.
+type Enclosure struct {
+	URL    string `json:"url"`
+	Type   string `json:"type,omitempty"`
+	Length int64  `json:"length,omitempty"`
+}
+
+func DatabasePostToPostDetail(dbPost database.Post) PostDetail {
+	detail := PostDetail{
+		Post:       DatabasePostToPost(dbPost),
+		Author:     dbPost.Author.String,
+		Categories: []string{},
+		Enclosures: []Enclosure{},
+		Tags:       []PostTag{},
+	}
+	// The columns only ever hold arrays written by the scraper; anything
+	// else is shown as empty.
+	json.Unmarshal(dbPost.Categories, &detail.Categories)
+	json.Unmarshal(dbPost.Enclosures, &detail.Enclosures)
+	return detail
+}
+
+// PostContent is the extracted article. HTML and Text are empty until
+// Status is "done".
+type PostContent struct {
+	Status             string     `json:"status"`
+	HTML               string     `json:"html,omitempty"`
+	Text               string     `json:"text,omitempty"`
+	WordCount          int        `json:"word_count"`
+	ReadingTimeMinutes int        `json:"reading_time_minutes"`
+	FetchedAt          *time.Time `json:"fetched_at,omitempty"`
+}
+
+func DatabasePostContentToPostContent(dbContent database.PostContent) PostContent {
+	var fetchedAt *time.Time
+	if dbContent.FetchedAt.Valid {
+		fetchedAt = &dbContent.FetchedAt.Time
+	}
+
+	return PostContent{
+		Status:             dbContent.Status,
+		HTML:               dbContent.Content.String,
+		Text:               dbContent.ContentText.String,
+		WordCount:          int(dbContent.WordCount),
+		ReadingTimeMinutes: content.ReadingTime(int(dbContent.WordCount)),
+		FetchedAt:          fetchedAt,
+	}
+}
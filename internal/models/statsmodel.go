@@ -0,0 +1,87 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/cyberkillua/dailyread/internal/database"
+	"github.com/google/uuid"
+)
+
+// Stats summarises every webpage and the posts collected from them.
+type Stats struct {
+	Totals   StatsTotals    `json:"totals"`
+	Webpages []WebpageStats `json:"webpages"`
+}
+
+type StatsTotals struct {
+	Webpages      int        `json:"webpages"`
+	Posts         int64      `json:"posts"`
+	UnreadPosts   int64      `json:"unread_posts"`
+	StarredPosts  int64      `json:"starred_posts"`
+	PostsLastDay  int64      `json:"posts_last_24h"`
+	PostsLastWeek int64      `json:"posts_last_7d"`
+	LastPostAt    *time.Time `json:"last_post_at"`
+}
+
+type WebpageStats struct {
+	ID            uuid.UUID  `json:"id"`
+	Name          string     `json:"name"`
+	Url           string     `json:"url"`
+	Posts         int64      `json:"posts"`
+	UnreadPosts   int64      `json:"unread_posts"`
+	PostsLastDay  int64      `json:"posts_last_24h"`
+	PostsLastWeek int64      `json:"posts_last_7d"`
+	LastPostAt    *time.Time `json:"last_post_at"`
+	// LastFetchedAt, LastFetchStatus and LastFetchError describe the most
+	// recent scrape; they are null until the webpage has been fetched.
+	LastFetchedAt   *time.Time `json:"last_fetched_at"`
+	LastFetchStatus *string    `json:"last_fetch_status"`
+	LastFetchError  *string    `json:"last_fetch_error"`
+}
+
+func DatabaseStatsToStats(totals database.GetPostTotalsRow, webpages []database.GetWebpageStatsRow) Stats {
+	stats := Stats{
+		Totals: StatsTotals{
+			Webpages:      len(webpages),
+			Posts:         totals.TotalPosts,
+			UnreadPosts:   totals.UnreadPosts,
+			StarredPosts:  totals.StarredPosts,
+			PostsLastDay:  totals.PostsLastDay,
+			PostsLastWeek: totals.PostsLastWeek,
+			LastPostAt:    nullTime(totals.LastPostAt),
+		},
+		Webpages: make([]WebpageStats, 0, len(webpages)),
+	}
+
+	for _, row := range webpages {
+		stats.Webpages = append(stats.Webpages, WebpageStats{
+			ID:              row.ID,
+			Name:            row.Name,
+			Url:             row.Url,
+			Posts:           row.TotalPosts,
+			UnreadPosts:     row.UnreadPosts,
+			PostsLastDay:    row.PostsLastDay,
+			PostsLastWeek:   row.PostsLastWeek,
+			LastPostAt:      nullTime(row.LastPostAt),
+			LastFetchedAt:   nullTime(row.LastUpdatedAt),
+			LastFetchStatus: nullString(row.LastFetchStatus),
+			LastFetchError:  nullString(row.LastFetchError),
+		})
+	}
+	return stats
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func nullString(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
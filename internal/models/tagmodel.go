@@ -0,0 +1,33 @@
+package models
+
+import "github.com/cyberkillua/dailyread/internal/database"
+
+// Tag is a topic with the number of posts carrying it.
+type Tag struct {
+	Name  string `json:"name"`
+	Posts int64  `json:"posts"`
+}
+
+// PostTag is a tag on a single post. Source is "category" for tags taken
+// from the feed and "keyword" for tags extracted from the post's text.
+type PostTag struct {
+	Name   string  `json:"name"`
+	Source string  `json:"source"`
+	Score  float64 `json:"score"`
+}
+
+func DatabaseTagsToTags(rows []database.ListTagsRow) []Tag {
+	tags := make([]Tag, 0, len(rows))
+	for _, row := range rows {
+		tags = append(tags, Tag{Name: row.Tag, Posts: row.Posts})
+	}
+	return tags
+}
+
+func DatabasePostTagsToTags(rows []database.PostTag) []PostTag {
+	tags := make([]PostTag, 0, len(rows))
+	for _, row := range rows {
+		tags = append(tags, PostTag{Name: row.Tag, Source: row.Source, Score: row.Score})
+	}
+	return tags
+}
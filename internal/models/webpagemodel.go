@@ -14,9 +14,31 @@ type Webpage struct {
 	Name      string    `json:"name"`
 	Url       string    `json:"url"`
 	Type      string    `json:"type"`
+	// SiteURL is the website the feed names as its own, once it has been
+	// scraped.
+	SiteURL *string `json:"site_url"`
+	// MaxItemAgeDays overrides the global ingest cutoff when set.
+	MaxItemAgeDays *int32 `json:"max_item_age_days"`
+	// UserAgent overrides the default User-Agent when set.
+	UserAgent *string `json:"user_agent"`
+	// FullText downloads the article behind each new post and extracts its
+	// content.
+	FullText bool `json:"full_text"`
+	// FolderIDs lists the folders the webpage is in. It is only filled in
+	// by the webpage listing.
+	FolderIDs []uuid.UUID `json:"folder_ids,omitempty"`
 }
 
 func DatabaseWebpageToWebpage(dbWebpage database.Webpage) Webpage {
+	var maxItemAgeDays *int32
+	if dbWebpage.MaxItemAgeDays.Valid {
+		maxItemAgeDays = &dbWebpage.MaxItemAgeDays.Int32
+	}
+	var userAgent *string
+	if dbWebpage.UserAgent.Valid {
+		userAgent = &dbWebpage.UserAgent.String
+	}
+
 	return Webpage{
 		ID:        dbWebpage.ID,
 		CreatedAt: dbWebpage.CreatedAt,
@@ -24,5 +46,10 @@ func DatabaseWebpageToWebpage(dbWebpage database.Webpage) Webpage {
 		Name:      dbWebpage.Name,
 		Url:       dbWebpage.Url,
 		Type:      dbWebpage.Type,
+		SiteURL:   nullString(dbWebpage.SiteUrl),
+
+		MaxItemAgeDays: maxItemAgeDays,
+		UserAgent:      userAgent,
+		FullText:       dbWebpage.FullText,
 	}
 }
@@ -1,41 +1,59 @@
 package server
 
 import (
-	"log"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/cyberkillua/dailyread/internal/config"
 	"github.com/cyberkillua/dailyread/internal/database"
 	"github.com/cyberkillua/dailyread/internal/handlers"
+	"github.com/cyberkillua/dailyread/internal/health"
+	"github.com/cyberkillua/dailyread/internal/metrics"
+	"github.com/cyberkillua/dailyread/internal/middleware"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/cors"
 )
 
 type Server struct {
 	config *config.Config
+	conn   *sql.DB
 	db     *database.Queries
+	health *health.Checker
 	router *chi.Mux
 }
 
-func New(cfg *config.Config, db *database.Queries) *Server {
+func New(cfg *config.Config, conn *sql.DB, db *database.Queries, checker *health.Checker) *Server {
 	router := chi.NewRouter()
 
+	router.Use(middleware.RequestID)
+	router.Use(middleware.AccessLog)
+	router.Use(middleware.Metrics)
+
 	// CORS middleware
 	router.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"*"},
-		ExposedHeaders:   []string{"Link"},
+		ExposedHeaders:   []string{"Link", "ETag", "Last-Modified", middleware.RequestIDHeader},
 		AllowCredentials: false,
 		MaxAge:           300,
 	}))
 
 	srv := &Server{
 		config: cfg,
+		conn:   conn,
 		db:     db,
+		health: checker,
 		router: router,
 	}
 
+	router.NotFound(handlers.HandlerNotFound)
+	router.MethodNotAllowed(handlers.HandlerMethodNotAllowed)
+
 	srv.setupRoutes()
 	return srv
 }
@@ -43,22 +61,89 @@ func New(cfg *config.Config, db *database.Queries) *Server {
 func (s *Server) setupRoutes() {
 	v1Router := chi.NewRouter()
 
-	apiConfig := &handlers.APIConfig{DB: s.db}
+	apiConfig := &handlers.APIConfig{Conn: s.conn, DB: s.db, Health: s.health, PublicURL: s.config.PublicURL}
 
 	v1Router.Get("/healthz", handlers.HandlerReadiness)
 	v1Router.Get("/err", handlers.HandlerErr)
+	v1Router.Get("/stats", apiConfig.GetStats)
+	v1Router.Get("/tags", apiConfig.ListTags)
+	v1Router.Get("/webpages", apiConfig.ListWebpages)
 	v1Router.Post("/webpages", apiConfig.CreateWebpage)
+	v1Router.Get("/webpages.opml", apiConfig.ExportOPML)
 	v1Router.Get("/posts", apiConfig.GetPost)
+	v1Router.Get("/posts/{postID}", apiConfig.GetPostByID)
+	v1Router.Put("/posts/{postID}/star", apiConfig.StarPost)
+	v1Router.Delete("/posts/{postID}/star", apiConfig.UnstarPost)
+	v1Router.Put("/posts/{postID}/read", apiConfig.MarkPostRead)
+	v1Router.Delete("/posts/{postID}/read", apiConfig.MarkPostUnread)
+
+	v1Router.Route("/folders", func(r chi.Router) {
+		r.Get("/", apiConfig.ListFolders)
+		r.Post("/", apiConfig.CreateFolder)
+		r.Put("/{folderID}", apiConfig.UpdateFolder)
+		r.Delete("/{folderID}", apiConfig.DeleteFolder)
+		r.Put("/{folderID}/webpages/{webpageID}", apiConfig.AddWebpageToFolder)
+		r.Delete("/{folderID}/webpages/{webpageID}", apiConfig.RemoveWebpageFromFolder)
+	})
+
+	v1Router.Route("/feeds", func(r chi.Router) {
+		r.Get("/all.{format}", apiConfig.GetAllFeed)
+		r.Get("/sources/{webpageID}.{format}", apiConfig.GetWebpageFeed)
+		r.Get("/tags/{tagfeed}", apiConfig.GetTagFeed)
+	})
 
 	s.router.Mount("/v1", v1Router)
+	mountOps(s.router, apiConfig)
 }
 
-func (s *Server) Start() error {
+// mountOps registers the endpoints used by monitoring and orchestration.
+func mountOps(r chi.Router, apiConfig *handlers.APIConfig) {
+	r.Handle("/metrics", metrics.Handler())
+	r.Get("/livez", handlers.HandlerLiveness)
+	r.Get("/readyz", apiConfig.HandlerReadyz)
+}
+
+// NewOpsHandler serves only the monitoring endpoints, for processes such as
+// the scrape worker that do not expose the API.
+func NewOpsHandler(checker *health.Checker) http.Handler {
+	router := chi.NewRouter()
+	router.NotFound(handlers.HandlerNotFound)
+	mountOps(router, &handlers.APIConfig{Health: checker})
+	return router
+}
+
+// shutdownTimeout bounds how long in-flight requests may take to finish
+// once the server is asked to stop.
+const shutdownTimeout = 30 * time.Second
+
+// Start serves the API until ctx is cancelled, then stops accepting
+// connections and waits up to shutdownTimeout for in-flight requests.
+func (s *Server) Start(ctx context.Context) error {
 	srv := &http.Server{
 		Handler: s.router,
 		Addr:    ":" + s.config.Port,
 	}
 
-	log.Printf("Server listening on port %v", s.config.Port)
-	return srv.ListenAndServe()
+	errs := make(chan error, 1)
+	go func() {
+		slog.Info("Server listening", "port", s.config.Port)
+		errs <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("Server shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cyberkillua/dailyread/internal/config"
+	"github.com/cyberkillua/dailyread/internal/database"
+)
+
+// recordingDriver answers every query with no rows and remembers the
+// arguments, so routes can be exercised without Postgres.
+type recordingDriver struct {
+	mu   sync.Mutex
+	args [][]driver.NamedValue
+}
+
+func (d *recordingDriver) Open(string) (driver.Conn, error) { return recordingConn{d}, nil }
+
+func (d *recordingDriver) queries() [][]driver.NamedValue {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.args
+}
+
+type recordingConn struct{ d *recordingDriver }
+
+func (c recordingConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c recordingConn) Close() error                        { return nil }
+func (c recordingConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+func (c recordingConn) QueryContext(_ context.Context, _ string, args []driver.NamedValue) (driver.Rows, error) {
+	c.d.mu.Lock()
+	c.d.args = append(c.d.args, args)
+	c.d.mu.Unlock()
+	return emptyRows{}, nil
+}
+
+type emptyRows struct{}
+
+func (emptyRows) Columns() []string         { return nil }
+func (emptyRows) Close() error              { return nil }
+func (emptyRows) Next([]driver.Value) error { return io.EOF }
+
+var (
+	testDriver     = &recordingDriver{}
+	registerDriver sync.Once
+)
+
+func newTestServer(t *testing.T) (*Server, *recordingDriver) {
+	t.Helper()
+	registerDriver.Do(func() { sql.Register("recording", testDriver) })
+	testDriver.mu.Lock()
+	testDriver.args = nil
+	testDriver.mu.Unlock()
+
+	conn, err := sql.Open("recording", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return New(&config.Config{PublicURL: "https://dailyread.example"}, conn, database.New(conn), nil), testDriver
+}
+
+func TestTagFeed(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		status      int
+		tag         string
+		contentType string
+	}{
+		{"plain tag", "/v1/feeds/tags/golang.rss", http.StatusOK, "golang", "application/rss+xml"},
+		{"dotted tag", "/v1/feeds/tags/node.js.rss", http.StatusOK, "node.js", "application/rss+xml"},
+		{"dotted tag atom", "/v1/feeds/tags/asp.net.atom", http.StatusOK, "asp.net", "application/atom+xml"},
+		{"dotted tag json", "/v1/feeds/tags/node.js.json", http.StatusOK, "node.js", "application/feed+json"},
+		{"escaped hash", "/v1/feeds/tags/c%23.xml", http.StatusOK, "c#", "application/rss+xml"},
+		{"plus signs", "/v1/feeds/tags/c++.rss", http.StatusOK, "c++", "application/rss+xml"},
+		{"normalized", "/v1/feeds/tags/Node.JS.rss", http.StatusOK, "node.js", "application/rss+xml"},
+		{"unknown format", "/v1/feeds/tags/node.js.html", http.StatusNotFound, "", ""},
+		{"no format", "/v1/feeds/tags/golang", http.StatusNotFound, "", ""},
+		{"no tag", "/v1/feeds/tags/.rss", http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, db := newTestServer(t)
+			rec := httptest.NewRecorder()
+			srv.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			queries := db.queries()
+			if tt.tag == "" {
+				if len(queries) != 0 {
+					t.Errorf("queried the database for a rejected request: %v", queries)
+				}
+				return
+			}
+			if len(queries) != 1 || queries[0][0].Value != tt.tag {
+				t.Errorf("queries = %v, want one for tag %q", queries, tt.tag)
+			}
+			if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, tt.contentType) {
+				t.Errorf("Content-Type = %q, want %q", ct, tt.contentType)
+			}
+		})
+	}
+}
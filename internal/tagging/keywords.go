@@ -0,0 +1,136 @@
+package tagging
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxTagLength bounds a tag in runes; longer categories are dropped.
+const maxTagLength = 50
+
+// titleWeight counts a word in the title as much as this many words in the
+// description.
+const titleWeight = 3
+
+// stopWords are common English words that never make a useful topic.
+var stopWords = map[string]bool{}
+
+func init() {
+	for _, word := range strings.Fields(`
+		about above after again against all also among and any are around
+		because been before being below best between both but can could
+		did does doing down during each even every few first for from
+		further get gets getting had has have having her here hers him his
+		how however into its itself just last like made make makes many
+		more most much must new next not now off once one only other our
+		ours out over own part really same she should since some still
+		such than that the their theirs them then there these they thing
+		things this those through too two under until use used using very
+		via want was way ways well were what when where which while who
+		whom why will with within without would year years yet you your
+		yours read continue reading post posts blog article`) {
+		stopWords[word] = true
+	}
+}
+
+// Normalize turns a feed category or keyword into the form tags are stored
+// in: lower case words joined by hyphens, so "Machine Learning" becomes
+// "machine-learning". Characters that commonly belong to a topic's name,
+// as in "c++", "c#" and "node.js", are kept. It returns "" for a tag that
+// is empty or too long.
+func Normalize(tag string) string {
+	words := strings.FieldsFunc(strings.ToLower(tag), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '+' && r != '#' && r != '.'
+	})
+	for i, word := range words {
+		words[i] = strings.Trim(word, ".")
+	}
+
+	normalized := strings.Trim(strings.Join(words, "-"), "-")
+	for strings.Contains(normalized, "--") {
+		normalized = strings.ReplaceAll(normalized, "--", "-")
+	}
+	if utf8.RuneCountInString(normalized) > maxTagLength {
+		return ""
+	}
+	return normalized
+}
+
+// Terms counts the candidate keywords in a post, with words in the title
+// weighted by titleWeight.
+func Terms(title, description string) map[string]int {
+	terms := make(map[string]int)
+	for _, term := range tokens(title) {
+		terms[term] += titleWeight
+	}
+	for _, term := range tokens(description) {
+		terms[term]++
+	}
+	return terms
+}
+
+// tokens splits text into lower case words that could be a topic: at least
+// three letters long, not a stop word and not just a number.
+func tokens(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	result := words[:0]
+	for _, word := range words {
+		if utf8.RuneCountInString(word) < 3 || utf8.RuneCountInString(word) > maxTagLength || stopWords[word] {
+			continue
+		}
+		if strings.IndexFunc(word, unicode.IsLetter) < 0 {
+			continue
+		}
+		result = append(result, word)
+	}
+	return result
+}
+
+// Keyword is a term extracted from a post with its TF-IDF score.
+type Keyword struct {
+	Term  string
+	Score float64
+}
+
+// Keywords ranks terms, as counted by Terms, by TF-IDF. documents holds the
+// number of posts each term appears in out of corpus posts. Terms found in
+// fewer than minDocuments posts are ignored, since a word no other post
+// uses says little about what a post is about; so are terms scoring below
+// minScore. At most limit keywords are returned, best first.
+func Keywords(terms map[string]int, documents map[string]int32, corpus int64, minDocuments int32, minScore float64, limit int) []Keyword {
+	total := 0
+	for _, count := range terms {
+		total += count
+	}
+
+	var keywords []Keyword
+	for term, count := range terms {
+		df := documents[term]
+		if df < minDocuments {
+			continue
+		}
+		idf := math.Log(float64(corpus+1) / float64(df+1))
+		score := float64(count) / float64(total) * idf
+		if score < minScore {
+			continue
+		}
+		keywords = append(keywords, Keyword{Term: term, Score: score})
+	}
+
+	sort.Slice(keywords, func(i, j int) bool {
+		if keywords[i].Score != keywords[j].Score {
+			return keywords[i].Score > keywords[j].Score
+		}
+		return keywords[i].Term < keywords[j].Term
+	})
+	if len(keywords) > limit {
+		keywords = keywords[:limit]
+	}
+	return keywords
+}
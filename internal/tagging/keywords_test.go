@@ -0,0 +1,158 @@
+package tagging
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"Go", "go"},
+		{"Machine Learning", "machine-learning"},
+		{"machine_learning", "machine-learning"},
+		{"Node.js", "node.js"},
+		{"ASP.NET", "asp.net"},
+		{"C#", "c#"},
+		{"F#", "f#"},
+		{"C++", "c++"},
+		{"--go--", "go"},
+		{"  Web   Development  ", "web-development"},
+		{"Web / Development", "web-development"},
+		{"rock 'n' roll", "rock-n-roll"},
+		{"Version 2.0.", "version-2.0"},
+		{".NET", "net"},
+		{"...", ""},
+		{"Économie", "économie"},
+		{"", ""},
+		{"!!!", ""},
+		{strings.Repeat("a", maxTagLength), strings.Repeat("a", maxTagLength)},
+		{strings.Repeat("a", maxTagLength+1), ""},
+		{strings.Repeat("é", maxTagLength), strings.Repeat("é", maxTagLength)},
+		{strings.Repeat("word ", 20), ""},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.tag); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeIsIdempotent(t *testing.T) {
+	for _, tag := range []string{"Node.js", "C#", "C++", "Machine Learning", "--go--", "Version 2.0."} {
+		once := Normalize(tag)
+		if twice := Normalize(once); twice != once {
+			t.Errorf("Normalize(Normalize(%q)) = %q, want %q", tag, twice, once)
+		}
+	}
+}
+
+func TestTerms(t *testing.T) {
+	got := Terms(
+		"Rust async runtime benchmarks",
+		"Comparing async runtimes in Rust, with benchmarks for tokio. Read more about it in 2024.",
+	)
+	want := map[string]int{
+		// Title words count titleWeight times, plus once per use in the
+		// description.
+		"rust":       titleWeight + 1,
+		"async":      titleWeight + 1,
+		"runtime":    titleWeight,
+		"benchmarks": titleWeight + 1,
+		"comparing":  1,
+		"runtimes":   1,
+		"tokio":      1,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Terms = %v, want %v", got, want)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		// Stop words, short words and numbers never become keywords.
+		{"The new API for the web in 2024", []string{"api", "web"}},
+		// Keywords are single words, so punctuation splits them.
+		{"Node.js and C++ on .NET", []string{"node", "net"}},
+		{"Crème brûlée", []string{"crème", "brûlée"}},
+		{"4k60 video", []string{"4k60", "video"}},
+		{strings.Repeat("x", maxTagLength+1) + " ok!", nil},
+	}
+	for _, tt := range tests {
+		if got := tokens(tt.text); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("tokens(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	terms := Terms(
+		"Rust async runtime benchmarks",
+		"Comparing async runtimes in Rust, with benchmarks for tokio.",
+	)
+	// Posts containing each term, out of a corpus of 100.
+	documents := map[string]int32{
+		"rust":       10,
+		"async":      20,
+		"runtime":    5,
+		"benchmarks": 30,
+		"comparing":  60,
+		"tokio":      1,
+	}
+	const corpus = 100
+
+	got := Keywords(terms, documents, corpus, 2, 0.15, 10)
+	var ranked []string
+	for _, keyword := range got {
+		ranked = append(ranked, keyword.Term)
+	}
+	// "comparing" is too common to score, "tokio" too rare to count and
+	// "runtimes" unseen.
+	if want := []string{"rust", "runtime", "async", "benchmarks"}; !reflect.DeepEqual(ranked, want) {
+		t.Fatalf("keywords = %v, want %v", ranked, want)
+	}
+
+	total := 0
+	for _, count := range terms {
+		total += count
+	}
+	want := float64(terms["rust"]) / float64(total) * math.Log(float64(corpus+1)/float64(documents["rust"]+1))
+	if math.Abs(got[0].Score-want) > 1e-9 {
+		t.Errorf("score of rust = %v, want %v", got[0].Score, want)
+	}
+
+	if limited := Keywords(terms, documents, corpus, 2, 0.15, 2); len(limited) != 2 || limited[1].Term != "runtime" {
+		t.Errorf("limited keywords = %v", limited)
+	}
+}
+
+func TestKeywordsTitleWeight(t *testing.T) {
+	// The same words, once in the title and once in the description: the
+	// title word outranks the description word of equal rarity.
+	terms := Terms("kubernetes", "terraform")
+	documents := map[string]int32{"kubernetes": 5, "terraform": 5}
+	got := Keywords(terms, documents, 100, 2, 0, 10)
+	if len(got) != 2 || got[0].Term != "kubernetes" || got[0].Score != float64(titleWeight)*got[1].Score {
+		t.Errorf("keywords = %v", got)
+	}
+}
+
+func TestKeywordsTies(t *testing.T) {
+	terms := map[string]int{"zebra": 1, "apple": 1, "mango": 1}
+	documents := map[string]int32{"zebra": 3, "apple": 3, "mango": 3}
+	got := Keywords(terms, documents, 50, 2, 0, 10)
+	var ranked []string
+	for _, keyword := range got {
+		ranked = append(ranked, keyword.Term)
+	}
+	if want := []string{"apple", "mango", "zebra"}; !reflect.DeepEqual(ranked, want) {
+		t.Errorf("tied keywords = %v, want %v", ranked, want)
+	}
+}
@@ -0,0 +1,264 @@
+// Package tagging assigns topic tags to posts from the categories their
+// feed gives them and from keywords extracted from their title and
+// description.
+package tagging
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/cyberkillua/dailyread/internal/database"
+)
+
+// Values stored in post_tags.source.
+const (
+	SourceCategory = "category"
+	SourceKeyword  = "keyword"
+)
+
+// lockID is the Postgres advisory lock that keeps two processes from
+// tagging at the same time.
+const lockID = 0x6461696c7904
+
+// Tagger tags every post that has not been tagged yet, every Interval.
+// Keywords are weighed by TF-IDF against the posts tagged so far, whose
+// document frequencies are kept in the term_documents table.
+type Tagger struct {
+	Conn     *sql.DB
+	Interval time.Duration
+	// MaxKeywords is the most keyword tags given to one post.
+	MaxKeywords int
+	// MinDocuments is the fewest posts a term must appear in to become a
+	// tag.
+	MinDocuments int32
+	// MinScore is the lowest TF-IDF score a keyword needs.
+	MinScore float64
+	// BatchSize bounds the posts tagged per transaction.
+	BatchSize int32
+}
+
+func New(conn *sql.DB, interval time.Duration) *Tagger {
+	return &Tagger{
+		Conn:         conn,
+		Interval:     interval,
+		MaxKeywords:  3,
+		MinDocuments: 2,
+		MinScore:     0.15,
+		BatchSize:    500,
+	}
+}
+
+// Start tags posts immediately and then every Interval until ctx is
+// cancelled.
+func (t *Tagger) Start(ctx context.Context) {
+	slog.Info("Starting tagger", "interval", t.Interval)
+
+	ticker := time.NewTicker(t.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := t.RunOnce(ctx); err != nil {
+			slog.Error("Tagging run failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce tags every untagged post and returns how many there were. It
+// returns without doing anything if another process holds the tagger lock.
+func (t *Tagger) RunOnce(ctx context.Context) (int, error) {
+	conn, err := t.Conn.Conn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("acquiring connection: %w", err)
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", lockID).Scan(&locked); err != nil {
+		return 0, fmt.Errorf("acquiring tagger lock: %w", err)
+	}
+	if !locked {
+		slog.Debug("Tagging run skipped, another process holds the lock")
+		return 0, nil
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID)
+
+	start := time.Now()
+	tagged, tags := 0, 0
+	for ctx.Err() == nil {
+		n, posts, err := t.tagBatch(ctx, conn)
+		if err != nil {
+			return tagged, err
+		}
+		tagged += posts
+		tags += n
+
+		if posts < int(t.BatchSize) {
+			break
+		}
+	}
+
+	if tagged > 0 {
+		slog.Info("Tagged posts",
+			"posts", tagged,
+			"tags", tags,
+			"duration", time.Since(start),
+		)
+	}
+	return tagged, nil
+}
+
+// tagBatch tags up to BatchSize posts in one transaction, so the document
+// frequencies never count a post whose tags were not stored. A post tagged
+// before has the terms it was counted with taken off first. It returns the
+// number of tags and of posts.
+func (t *Tagger) tagBatch(ctx context.Context, conn *sql.Conn) (int, int, error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+	db := database.New(tx)
+
+	posts, err := db.ListUntaggedPosts(ctx, t.BatchSize)
+	if err != nil {
+		return 0, 0, fmt.Errorf("listing untagged posts: %w", err)
+	}
+	if len(posts) == 0 {
+		return 0, 0, nil
+	}
+
+	postTerms := make([]map[string]int, len(posts))
+	termsParams := database.SetPostTermsParams{
+		PostIds: make([]uuid.UUID, len(posts)),
+		Terms:   make([]string, len(posts)),
+	}
+	changes := make(map[string]int32)
+	for i, post := range posts {
+		var counted []string
+		if err := json.Unmarshal(post.Terms, &counted); err != nil {
+			return 0, 0, fmt.Errorf("reading counted terms of post %s: %w", post.ID, err)
+		}
+		for _, term := range counted {
+			changes[term]--
+		}
+
+		postTerms[i] = Terms(post.Title, post.DescriptionText.String)
+		terms := make([]string, 0, len(postTerms[i]))
+		for term := range postTerms[i] {
+			changes[term]++
+			terms = append(terms, term)
+		}
+		sort.Strings(terms)
+		encoded, err := json.Marshal(terms)
+		if err != nil {
+			return 0, 0, fmt.Errorf("encoding terms: %w", err)
+		}
+		termsParams.PostIds[i] = post.ID
+		termsParams.Terms[i] = string(encoded)
+	}
+
+	// Terms are written in a fixed order so concurrent upserts of the same
+	// rows cannot deadlock.
+	changed := make([]string, 0, len(changes))
+	for term, change := range changes {
+		if change != 0 {
+			changed = append(changed, term)
+		}
+	}
+	sort.Strings(changed)
+	counts := make([]int32, len(changed))
+	for i, term := range changed {
+		counts[i] = changes[term]
+	}
+
+	err = db.AddTermDocuments(ctx, database.AddTermDocumentsParams{
+		Terms:     changed,
+		Documents: counts,
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("counting terms: %w", err)
+	}
+	if err := db.SetPostTerms(ctx, termsParams); err != nil {
+		return 0, 0, fmt.Errorf("recording counted terms: %w", err)
+	}
+
+	terms := make([]string, 0, len(changes))
+	for term := range changes {
+		terms = append(terms, term)
+	}
+	rows, err := db.GetTermDocuments(ctx, terms)
+	if err != nil {
+		return 0, 0, fmt.Errorf("loading term counts: %w", err)
+	}
+	documents := make(map[string]int32, len(rows))
+	for _, row := range rows {
+		documents[row.Term] = row.Documents
+	}
+	corpus, err := db.CountTaggedPosts(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("counting tagged posts: %w", err)
+	}
+	corpus += int64(len(posts))
+
+	params := database.InsertPostTagsParams{Now: time.Now().UTC()}
+	ids := make([]uuid.UUID, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+		seen := make(map[string]bool)
+		add := func(tag, source string, score float64) {
+			if tag == "" || seen[tag] {
+				return
+			}
+			seen[tag] = true
+			params.PostIds = append(params.PostIds, post.ID)
+			params.Tags = append(params.Tags, tag)
+			params.Sources = append(params.Sources, source)
+			params.Scores = append(params.Scores, score)
+		}
+
+		var categories []string
+		if err := json.Unmarshal(post.Categories, &categories); err != nil {
+			slog.Warn("Ignoring unreadable categories", "post_id", post.ID, "error", err)
+		}
+		for _, category := range categories {
+			add(Normalize(category), SourceCategory, 1)
+		}
+		for _, keyword := range Keywords(postTerms[i], documents, corpus, t.MinDocuments, t.MinScore, t.MaxKeywords) {
+			add(keyword.Term, SourceKeyword, keyword.Score)
+		}
+	}
+
+	if err := db.DeletePostTags(ctx, ids); err != nil {
+		return 0, 0, fmt.Errorf("clearing previous tags: %w", err)
+	}
+	if len(params.PostIds) > 0 {
+		if err := db.InsertPostTags(ctx, params); err != nil {
+			return 0, 0, fmt.Errorf("storing tags: %w", err)
+		}
+	}
+	err = db.MarkPostsTagged(ctx, database.MarkPostsTaggedParams{
+		Now: params.Now,
+		Ids: ids,
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("marking posts tagged: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("committing tags: %w", err)
+	}
+	return len(params.PostIds), len(posts), nil
+}
@@ -0,0 +1,125 @@
+// Package trending periodically ranks recent posts so the trending listing
+// can be served from an indexed score column.
+package trending
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/cyberkillua/dailyread/internal/database"
+	"github.com/cyberkillua/dailyread/internal/metrics"
+)
+
+// lockID is the Postgres advisory lock that keeps two processes from
+// scoring at the same time.
+const lockID = 0x6461696c7902
+
+// Scorer recomputes post scores every Interval. A post's score grows with
+// the number of sources carrying the same link or headline and with the
+// reader's stars and reads, and decays with age like a Hacker News ranking.
+type Scorer struct {
+	Conn     *sql.DB
+	Interval time.Duration
+	// Window is how far back posts are scored; older posts drop to zero
+	// and out of the trending listing.
+	Window time.Duration
+	// Gravity is the exponent of the age decay. Higher values favour
+	// newer posts.
+	Gravity float64
+	// CoverageWeight multiplies the log of the number of sources carrying
+	// the same story.
+	CoverageWeight float64
+	StarWeight     float64
+	ReadWeight     float64
+}
+
+func New(conn *sql.DB, window, interval time.Duration) *Scorer {
+	return &Scorer{
+		Conn:           conn,
+		Interval:       interval,
+		Window:         window,
+		Gravity:        1.5,
+		CoverageWeight: 1,
+		StarWeight:     1,
+		ReadWeight:     0.25,
+	}
+}
+
+// Start scores posts immediately and then every Interval until ctx is
+// cancelled.
+func (s *Scorer) Start(ctx context.Context) {
+	slog.Info("Starting trending scorer",
+		"window", s.Window,
+		"interval", s.Interval,
+	)
+
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.RunOnce(ctx); err != nil {
+			slog.Error("Trending run failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce rescores the posts inside the window, clears the scores of those
+// that have left it and returns how many posts were scored. It returns
+// without doing anything if another process holds the scorer lock.
+func (s *Scorer) RunOnce(ctx context.Context) (int64, error) {
+	conn, err := s.Conn.Conn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("acquiring connection: %w", err)
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", lockID).Scan(&locked); err != nil {
+		return 0, fmt.Errorf("acquiring trending lock: %w", err)
+	}
+	if !locked {
+		slog.Debug("Trending run skipped, another process holds the lock")
+		return 0, nil
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID)
+
+	start := time.Now()
+	now := start.UTC()
+	windowStart := now.Add(-s.Window)
+	db := database.New(conn)
+
+	scored, err := db.ScoreRecentPosts(ctx, database.ScoreRecentPostsParams{
+		Now:            now,
+		WindowStart:    windowStart,
+		CoverageWeight: s.CoverageWeight,
+		StarWeight:     s.StarWeight,
+		ReadWeight:     s.ReadWeight,
+		Gravity:        s.Gravity,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("scoring posts: %w", err)
+	}
+	reset, err := db.ResetStalePostScores(ctx, windowStart)
+	if err != nil {
+		return scored, fmt.Errorf("resetting stale scores: %w", err)
+	}
+
+	metrics.TrendingLastRun.SetToCurrentTime()
+	metrics.TrendingLastScored.Set(float64(scored))
+	slog.Info("Trending run finished",
+		"window_start", windowStart,
+		"scored", scored,
+		"reset", reset,
+		"duration", time.Since(start),
+	)
+	return scored, nil
+}
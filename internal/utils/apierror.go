@@ -0,0 +1,187 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/lib/pq"
+)
+
+// ProblemContentType is the media type of RFC 7807 error responses.
+const ProblemContentType = "application/problem+json"
+
+// Stable, machine readable error codes. Clients should switch on these
+// rather than on the human readable detail.
+const (
+	CodeBadRequest       = "bad_request"
+	CodeInvalidBody      = "invalid_body"
+	CodeValidationFailed = "validation_failed"
+	CodeInvalidID        = "invalid_id"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeNotAcceptable    = "not_acceptable"
+	CodeConflict         = "conflict"
+	CodeTimeout          = "timeout"
+	CodeInternal         = "internal_error"
+)
+
+// APIError is an error that knows how it should be presented to API clients.
+// Err holds the underlying cause; it is logged but never sent to the client.
+type APIError struct {
+	Status int
+	Code   string
+	Detail string
+	Fields []FieldError
+	Err    error
+}
+
+// FieldError describes why a single request field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Detail, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Detail)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+func NewAPIError(status int, code, detail string) *APIError {
+	return &APIError{Status: status, Code: code, Detail: detail}
+}
+
+func ErrBadRequest(code, detail string) *APIError {
+	return NewAPIError(http.StatusBadRequest, code, detail)
+}
+
+func ErrNotFound(detail string) *APIError {
+	return NewAPIError(http.StatusNotFound, CodeNotFound, detail)
+}
+
+func ErrValidation(fields ...FieldError) *APIError {
+	return &APIError{
+		Status: http.StatusUnprocessableEntity,
+		Code:   CodeValidationFailed,
+		Detail: "One or more fields are invalid",
+		Fields: fields,
+	}
+}
+
+func ErrInternal(err error) *APIError {
+	return &APIError{
+		Status: http.StatusInternalServerError,
+		Code:   CodeInternal,
+		Detail: "An internal error occurred",
+		Err:    err,
+	}
+}
+
+// DatabaseError maps an error returned by a query to an API error. resource
+// names the thing being read or written and is used in client messages.
+func DatabaseError(err error, resource string) *APIError {
+	if errors.Is(err, sql.ErrNoRows) {
+		return &APIError{
+			Status: http.StatusNotFound,
+			Code:   CodeNotFound,
+			Detail: fmt.Sprintf("%s not found", resource),
+			Err:    err,
+		}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &APIError{
+			Status: http.StatusGatewayTimeout,
+			Code:   CodeTimeout,
+			Detail: "The database did not respond in time",
+			Err:    err,
+		}
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "unique_violation":
+			return &APIError{
+				Status: http.StatusConflict,
+				Code:   CodeConflict,
+				Detail: fmt.Sprintf("%s already exists", resource),
+				Err:    err,
+			}
+		case "foreign_key_violation":
+			return &APIError{
+				Status: http.StatusConflict,
+				Code:   CodeConflict,
+				Detail: fmt.Sprintf("%s references a missing or in-use record", resource),
+				Err:    err,
+			}
+		case "not_null_violation", "check_violation", "string_data_right_truncation", "invalid_text_representation":
+			return &APIError{
+				Status: http.StatusBadRequest,
+				Code:   CodeBadRequest,
+				Detail: fmt.Sprintf("Invalid %s", resource),
+				Err:    err,
+			}
+		}
+	}
+
+	return ErrInternal(err)
+}
+
+type problemDetails struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+type problemEncoder struct {
+	JSONEncoder
+}
+
+func (problemEncoder) ContentType() string { return ProblemContentType }
+
+// RespondWithError writes err as an RFC 7807 problem document. Errors that
+// are not an *APIError are treated as internal errors so their message never
+// reaches the client.
+func RespondWithError(w http.ResponseWriter, r *http.Request, err error) {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		apiErr = ErrInternal(err)
+	}
+
+	requestID := RequestIDFromContext(r.Context())
+	if apiErr.Status > 499 {
+		slog.ErrorContext(r.Context(), "Responding with error",
+			"request_id", requestID,
+			"status", apiErr.Status,
+			"code", apiErr.Code,
+			"error", apiErr.Err,
+		)
+	}
+
+	RespondWithEncoder(w, apiErr.Status, problemEncoder{}, problemDetails{
+		Type:      "urn:dailyread:problem:" + apiErr.Code,
+		Title:     http.StatusText(apiErr.Status),
+		Status:    apiErr.Status,
+		Detail:    apiErr.Detail,
+		Instance:  r.URL.Path,
+		Code:      apiErr.Code,
+		RequestID: requestID,
+		Errors:    apiErr.Fields,
+	})
+}
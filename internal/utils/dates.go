@@ -0,0 +1,295 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLayouts are tried in order against a normalized date string: weekday
+// names are removed, month names are reduced to English abbreviations and
+// time zone names are replaced by numeric offsets, so each layout only has
+// to cover one spelling of every field.
+var dateLayouts = []string{
+	// RFC 1123 / RFC 822 family, as used by RSS.
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04 -0700",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006 15:04",
+	"2 Jan 2006",
+
+	// RFC 850.
+	"2-Jan-06 15:04:05 -0700",
+	"2-Jan-2006 15:04:05 -0700",
+
+	// ISO 8601 / RFC 3339, as used by Atom and dc:date.
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999-0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999 -0700",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+	"2006/01/02",
+
+	// Ruby and ANSI C.
+	"Jan 2 15:04:05 -0700 2006",
+	"Jan 2 15:04:05 2006",
+
+	// Human written dates.
+	"Jan 2, 2006 15:04:05 -0700",
+	"Jan 2, 2006 15:04:05",
+	"Jan 2, 2006 3:04 PM -0700",
+	"Jan 2, 2006 3:04 PM",
+	"Jan 2, 2006 15:04",
+	"Jan 2, 2006",
+	"Jan 2 2006",
+	"2 Jan, 2006",
+	"02.01.2006 15:04:05",
+	"02.01.2006 15:04",
+	"02.01.2006",
+}
+
+// monthNames maps month names and abbreviations in English, French,
+// German, Spanish, Portuguese, Italian and Dutch to the English
+// abbreviation.
+var monthNames = map[string]string{}
+
+func init() {
+	months := [][]string{
+		{"january", "jan", "janvier", "janv", "januar", "jänner", "jaenner", "enero", "ene", "janeiro", "gennaio", "gen", "januari"},
+		{"february", "feb", "février", "fevrier", "févr", "fevr", "fév", "fev", "februar", "febrero", "fevereiro", "febbraio", "februari"},
+		{"march", "mar", "mars", "märz", "maerz", "mär", "mrz", "marzo", "março", "marco", "maart", "mrt"},
+		{"april", "apr", "avril", "avr", "abril", "abr", "aprile"},
+		{"may", "mai", "mayo", "maio", "maggio", "mag", "mei"},
+		{"june", "jun", "juin", "juni", "junio", "junho", "giugno", "giu"},
+		{"july", "jul", "juillet", "juil", "juli", "julio", "julho", "luglio", "lug"},
+		{"august", "aug", "août", "aout", "agosto", "ago", "augustus"},
+		{"september", "sep", "sept", "septembre", "septiembre", "setiembre", "setembro", "settembre", "set"},
+		{"october", "oct", "octobre", "oktober", "okt", "octubre", "outubro", "out", "ottobre", "ott"},
+		{"november", "nov", "novembre", "noviembre", "novembro"},
+		{"december", "dec", "décembre", "decembre", "déc", "dezember", "dez", "diciembre", "dic", "dezembro", "dicembre"},
+	}
+	english := []string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+
+	for i, names := range months {
+		for _, name := range names {
+			monthNames[name] = english[i]
+		}
+	}
+}
+
+// weekdayNames are dropped wherever they appear; publishers regularly get
+// them wrong, and the date alone is enough.
+var weekdayNames = map[string]bool{}
+
+func init() {
+	for _, name := range strings.Fields(`
+		monday tuesday wednesday thursday friday saturday sunday
+		mon tue tues wed thu thur thurs fri sat sun
+		lundi mardi mercredi jeudi vendredi samedi dimanche
+		montag dienstag mittwoch donnerstag freitag samstag sonntag
+		mo di mi do fr sa so
+		lunes martes miércoles miercoles jueves viernes sábado sabado domingo
+		segunda terça terca quarta quinta sexta
+		lunedì lunedi martedì martedi mercoledì mercoledi giovedì giovedi venerdì venerdi
+		maandag dinsdag woensdag donderdag vrijdag zaterdag zondag`) {
+		weekdayNames[name] = true
+	}
+}
+
+// dateFillers are connecting words in dates such as "2 de enero de 2024".
+var dateFillers = map[string]bool{"de": true, "del": true, "of": true, "the": true}
+
+// zoneOffsets resolves time zone abbreviations. time.Parse silently gives
+// unknown abbreviations a zero offset, so they are rewritten before parsing.
+var zoneOffsets = map[string]string{
+	"UT": "+0000", "UTC": "+0000", "GMT": "+0000", "Z": "+0000", "WET": "+0000",
+	"BST": "+0100", "IST": "+0530", "WEST": "+0100", "CET": "+0100", "MET": "+0100",
+	"CEST": "+0200", "MEST": "+0200", "EET": "+0200", "SAST": "+0200",
+	"EEST": "+0300", "MSK": "+0300",
+	"PKT": "+0500", "ICT": "+0700", "WIB": "+0700",
+	"CST": "-0600", "CDT": "-0500",
+	"HKT": "+0800", "SGT": "+0800", "AWST": "+0800", "PHT": "+0800",
+	"JST": "+0900", "KST": "+0900",
+	"ACST": "+0930", "ACDT": "+1030", "AEST": "+1000", "AEDT": "+1100",
+	"NZST": "+1200", "NZDT": "+1300",
+	"EST": "-0500", "EDT": "-0400",
+	"MST": "-0700", "MDT": "-0600",
+	"PST": "-0800", "PDT": "-0700",
+	"AKST": "-0900", "AKDT": "-0800",
+	"HST": "-1000",
+	"AST": "-0400", "ADT": "-0300",
+	"NST": "-0330", "NDT": "-0230",
+	"BRT": "-0300", "ART": "-0300",
+}
+
+var (
+	ordinalSuffix  = regexp.MustCompile(`\b(\d{1,2})(st|nd|rd|th)\b`)
+	trailingParens = regexp.MustCompile(`\s*\([^)]*\)\s*$`)
+	// zoneWithOffset matches forms such as "GMT+2", "UTC-05:30" or "+05:30".
+	zoneWithOffset = regexp.MustCompile(`^(?:[A-Za-z]{1,5})?([+-])(\d{1,2})(?::?(\d{2}))?$`)
+	allDigits      = regexp.MustCompile(`^\d+$`)
+)
+
+// parseDate parses the dates found in real world feeds, which only
+// loosely follow RFC 822 or RFC 3339. Times without a zone are read as UTC.
+func parseDate(value string) (time.Time, error) {
+	normalized := normalizeDate(value)
+	if normalized == "" {
+		return time.Time{}, fmt.Errorf("empty date")
+	}
+
+	if allDigits.MatchString(normalized) {
+		if t, ok := parseUnixDate(normalized); ok {
+			return t, nil
+		}
+	}
+
+	for _, layout := range dateLayouts {
+		t, err := time.Parse(layout, normalized)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unknown date format: %s", value)
+}
+
+// normalizeDate rewrites a date into the reduced vocabulary used by
+// dateLayouts.
+func normalizeDate(value string) string {
+	value = strings.TrimSpace(value)
+	value = trailingParens.ReplaceAllString(value, "")
+	value = ordinalSuffix.ReplaceAllString(value, "$1")
+
+	// ISO dates keep their shape; only a trailing "z" needs fixing.
+	if len(value) >= 10 && value[4] == '-' && value[7] == '-' {
+		value = strings.Replace(value, "t", "T", 1)
+		if strings.HasSuffix(value, "z") {
+			value = value[:len(value)-1] + "Z"
+		}
+		return normalizeISOZone(value)
+	}
+
+	fields := strings.Fields(value)
+	out := make([]string, 0, len(fields))
+	for i, field := range fields {
+		comma := strings.HasSuffix(field, ",")
+		word := strings.TrimRight(field, ",.")
+		lower := strings.ToLower(word)
+
+		if weekdayNames[lower] || dateFillers[lower] {
+			continue
+		}
+		// A leading word followed by a comma is a weekday, possibly in a
+		// language we don't know, unless it is the only month in the date;
+		// the French "mar." in "mar., 02 janv. 2024" is a Tuesday.
+		if i == 0 && comma && !containsDigit(word) {
+			if _, ok := monthNames[lower]; !ok || countMonths(fields[1:]) > 0 {
+				continue
+			}
+		}
+
+		if month, ok := monthNames[lower]; ok {
+			word = month
+		} else if strings.Contains(word, "-") && !zoneWithOffset.MatchString(word) {
+			word = normalizeDashedMonth(word)
+		} else if i > 0 {
+			word = normalizeZone(word)
+		}
+
+		if lower == "am" || lower == "pm" {
+			word = strings.ToUpper(lower)
+		}
+
+		if comma {
+			word += ","
+		}
+		out = append(out, word)
+	}
+
+	return strings.Join(out, " ")
+}
+
+// normalizeDashedMonth handles RFC 850 style "02-janv-24".
+func normalizeDashedMonth(word string) string {
+	parts := strings.Split(word, "-")
+	for i, part := range parts {
+		if month, ok := monthNames[strings.ToLower(strings.TrimRight(part, "."))]; ok {
+			parts[i] = month
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// normalizeZone rewrites a zone abbreviation or offset to the "-0700" form.
+func normalizeZone(word string) string {
+	if offset, ok := zoneOffsets[strings.ToUpper(word)]; ok {
+		return offset
+	}
+
+	m := zoneWithOffset.FindStringSubmatch(word)
+	if m == nil {
+		return word
+	}
+	prefix := strings.ToUpper(strings.TrimRight(word, "+-0123456789:"))
+	if prefix != "" && prefix != "GMT" && prefix != "UTC" && prefix != "UT" {
+		return word
+	}
+
+	hours, _ := strconv.Atoi(m[2])
+	minutes := 0
+	if m[3] != "" {
+		minutes, _ = strconv.Atoi(m[3])
+	}
+	return fmt.Sprintf("%s%02d%02d", m[1], hours, minutes)
+}
+
+// normalizeISOZone replaces a zone abbreviation that follows an ISO date
+// with a space, e.g. "2024-01-02 15:04:05 EST".
+func normalizeISOZone(value string) string {
+	idx := strings.LastIndex(value, " ")
+	if idx < 0 || idx < 10 {
+		return value
+	}
+	return value[:idx+1] + normalizeZone(value[idx+1:])
+}
+
+func parseUnixDate(value string) (time.Time, bool) {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	switch len(value) {
+	case 9, 10:
+		return time.Unix(n, 0).UTC(), true
+	case 13:
+		return time.UnixMilli(n).UTC(), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func countMonths(fields []string) int {
+	n := 0
+	for _, field := range fields {
+		if _, ok := monthNames[strings.ToLower(strings.TrimRight(field, ",."))]; ok {
+			n++
+		}
+	}
+	return n
+}
+
+func containsDigit(s string) bool {
+	return strings.IndexAny(s, "0123456789") >= 0
+}
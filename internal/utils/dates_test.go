@@ -0,0 +1,222 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		// RFC 1123 / RFC 822 family.
+		{"rfc1123", "Tue, 02 Jan 2024 15:04:05 GMT", "2024-01-02T15:04:05Z"},
+		{"rfc1123 single digit day", "Tue, 2 Jan 2024 15:04:05 GMT", "2024-01-02T15:04:05Z"},
+		{"rfc1123z", "Tue, 02 Jan 2024 15:04:05 +0100", "2024-01-02T14:04:05Z"},
+		{"without seconds", "Tue, 2 Jan 2024 15:04 +0000", "2024-01-02T15:04:00Z"},
+		{"two digit year", "Tue, 02 Jan 24 15:04:05 +0000", "2024-01-02T15:04:05Z"},
+		{"without zone", "02 Jan 2024 15:04:05", "2024-01-02T15:04:05Z"},
+		{"date only", "2 Jan 2024", "2024-01-02T00:00:00Z"},
+		{"full month and weekday", "Tuesday, 2 January 2024 15:04:05 GMT", "2024-01-02T15:04:05Z"},
+		{"ordinal day", "Jan 2nd, 2024", "2024-01-02T00:00:00Z"},
+		{"trailing zone name", "Tue, 02 Jan 2024 15:04:05 +0000 (UTC)", "2024-01-02T15:04:05Z"},
+
+		// RFC 850.
+		{"rfc850", "Tuesday, 02-Jan-24 15:04:05 GMT", "2024-01-02T15:04:05Z"},
+		{"rfc850 four digit year", "02-Jan-2024 15:04:05 +0000", "2024-01-02T15:04:05Z"},
+
+		// ISO 8601 / RFC 3339.
+		{"rfc3339", "2024-01-02T15:04:05Z", "2024-01-02T15:04:05Z"},
+		{"rfc3339 offset", "2024-01-02T15:04:05+02:00", "2024-01-02T13:04:05Z"},
+		{"rfc3339 fraction", "2024-01-02T15:04:05.123Z", "2024-01-02T15:04:05.123Z"},
+		{"compact offset", "2024-01-02T15:04:05-0500", "2024-01-02T20:04:05Z"},
+		{"lowercase t and z", "2024-01-02t15:04:05z", "2024-01-02T15:04:05Z"},
+		{"iso without seconds", "2024-01-02T15:04Z", "2024-01-02T15:04:00Z"},
+		{"iso without zone", "2024-01-02T15:04:05", "2024-01-02T15:04:05Z"},
+		{"space separated", "2024-01-02 15:04:05", "2024-01-02T15:04:05Z"},
+		{"space separated zone name", "2024-01-02 15:04:05 EST", "2024-01-02T20:04:05Z"},
+		{"space separated offset", "2024-01-02 15:04:05+01:00", "2024-01-02T14:04:05Z"},
+		{"space separated minutes", "2024-01-02 15:04", "2024-01-02T15:04:00Z"},
+		{"iso date", "2024-01-02", "2024-01-02T00:00:00Z"},
+		{"slashes", "2024/01/02 15:04:05", "2024-01-02T15:04:05Z"},
+		{"slashes date", "2024/01/02", "2024-01-02T00:00:00Z"},
+
+		// Ruby and ANSI C.
+		{"ruby", "Tue Jan 2 15:04:05 -0700 2024", "2024-01-02T22:04:05Z"},
+		{"ansic", "Tue Jan 2 15:04:05 2024", "2024-01-02T15:04:05Z"},
+
+		// Human written dates.
+		{"month first", "January 2, 2024", "2024-01-02T00:00:00Z"},
+		{"month first time", "Jan 2, 2024 15:04", "2024-01-02T15:04:00Z"},
+		{"twelve hour", "Jan 2, 2024 3:04 PM", "2024-01-02T15:04:00Z"},
+		{"twelve hour lowercase", "Jan 2, 2024 3:04 pm EST", "2024-01-02T20:04:00Z"},
+		{"month first no comma", "Jan 2 2024", "2024-01-02T00:00:00Z"},
+		{"day first comma", "2 Jan, 2024", "2024-01-02T00:00:00Z"},
+		{"dotted", "02.01.2024", "2024-01-02T00:00:00Z"},
+		{"dotted time", "02.01.2024 15:04", "2024-01-02T15:04:00Z"},
+
+		// Other languages.
+		{"french", "mar., 02 janv. 2024 15:04:05 +0100", "2024-01-02T14:04:05Z"},
+		{"french full", "mardi 2 janvier 2024", "2024-01-02T00:00:00Z"},
+		{"french accent", "13 févr. 2024", "2024-02-13T00:00:00Z"},
+		{"french march", "mar., 05 mars 2024", "2024-03-05T00:00:00Z"},
+		{"german", "Di, 02 Jan 2024 15:04:05 +0100", "2024-01-02T14:04:05Z"},
+		{"german full", "Dienstag, 5. März 2024", "2024-03-05T00:00:00Z"},
+		{"german dotted month", "02. Dez. 2024", "2024-12-02T00:00:00Z"},
+		{"spanish", "martes, 2 de enero de 2024", "2024-01-02T00:00:00Z"},
+		{"spanish abbreviation", "mié, 03 abr 2024 10:00:00 +0200", "2024-04-03T08:00:00Z"},
+		{"dutch", "dinsdag 2 januari 2024", "2024-01-02T00:00:00Z"},
+		{"dutch abbreviation", "wo, 3 mrt 2024 10:00 +0100", "2024-03-03T09:00:00Z"},
+		{"unknown weekday", "xyz, 02 Jan 2024", "2024-01-02T00:00:00Z"},
+		{"dashed french month", "02-janv-24 15:04:05 +0000", "2024-01-02T15:04:05Z"},
+
+		// Zones.
+		{"pst", "Tue, 02 Jan 2024 15:04:05 PST", "2024-01-02T23:04:05Z"},
+		{"edt", "Tue, 02 Jan 2024 15:04:05 EDT", "2024-01-02T19:04:05Z"},
+		{"cest", "Tue, 02 Jan 2024 15:04:05 CEST", "2024-01-02T13:04:05Z"},
+		{"ist", "Tue, 02 Jan 2024 15:04:05 IST", "2024-01-02T09:34:05Z"},
+		{"ut", "Tue, 02 Jan 2024 15:04:05 UT", "2024-01-02T15:04:05Z"},
+		{"z", "Tue, 02 Jan 2024 15:04:05 Z", "2024-01-02T15:04:05Z"},
+		{"lowercase zone", "Tue, 02 Jan 2024 15:04:05 gmt", "2024-01-02T15:04:05Z"},
+		{"gmt offset hours", "Tue, 02 Jan 2024 15:04:05 GMT+2", "2024-01-02T13:04:05Z"},
+		{"gmt negative offset", "Tue, 02 Jan 2024 15:04:05 GMT-7", "2024-01-02T22:04:05Z"},
+		{"utc offset minutes", "Tue, 02 Jan 2024 15:04:05 UTC-05:30", "2024-01-02T20:34:05Z"},
+		{"colon offset", "Tue, 02 Jan 2024 15:04:05 +05:30", "2024-01-02T09:34:05Z"},
+
+		// Unix timestamps.
+		{"unix seconds", "1704207845", "2024-01-02T15:04:05Z"},
+		{"unix milliseconds", "1704207845123", "2024-01-02T15:04:05.123Z"},
+		{"unix padded", " 1704207845 ", "2024-01-02T15:04:05Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDate(tt.value)
+			if err != nil {
+				t.Fatalf("parseDate(%q): %v", tt.value, err)
+			}
+			want, err := time.Parse(time.RFC3339Nano, tt.want)
+			if err != nil {
+				t.Fatalf("bad want %q: %v", tt.want, err)
+			}
+			if !got.Equal(want) {
+				t.Errorf("parseDate(%q) = %s, want %s", tt.value, got.UTC().Format(time.RFC3339Nano), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDateRejects(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"   ",
+		"yesterday",
+		"not a date at all",
+		"12345",
+		"2024-13-45",
+		"Tue, 32 Jan 2024",
+		"Tue, 02 Foo 2024 15:04:05 GMT",
+	} {
+		if got, err := parseDate(value); err == nil {
+			t.Errorf("parseDate(%q) = %s, want error", value, got)
+		}
+	}
+}
+
+func TestNormalizeDate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		// The leading "mar." is a weekday, since "janv." is the month.
+		{"mar., 02 janv. 2024", "02 Jan 2024"},
+		// Here "mar." is the only month, so it is kept.
+		{"mar., 02 2024", "Mar, 02 2024"},
+		{"Tue, 2 Jan 2024 15:04:05 GMT+2", "2 Jan 2024 15:04:05 +0200"},
+		{"Tue, 2 Jan 2024 15:04:05 UTC-05:30", "2 Jan 2024 15:04:05 -0530"},
+		{"Tue, 2 Jan 2024 15:04:05 XYZ", "2 Jan 2024 15:04:05 XYZ"},
+		{"2024-01-02 15:04:05 PDT", "2024-01-02 15:04:05 -0700"},
+		{"2024-01-02t15:04:05z", "2024-01-02T15:04:05Z"},
+		{"martes, 2 de enero de 2024", "2 Jan 2024"},
+		{"Jan 2nd, 2024 3:04 pm", "Jan 2, 2024 3:04 PM"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeDate(tt.value); got != tt.want {
+			t.Errorf("normalizeDate(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestItemDate(t *testing.T) {
+	tests := []struct {
+		name   string
+		item   RSSItem
+		want   string
+		wantOK bool
+	}{
+		{
+			name:   "pubDate",
+			item:   RSSItem{PubDate: "Tue, 02 Jan 2024 15:04:05 GMT", DCDate: "2023-01-01", Updated: "2022-01-01"},
+			want:   "2024-01-02T15:04:05Z",
+			wantOK: true,
+		},
+		{
+			name:   "dc:date when pubDate is missing",
+			item:   RSSItem{DCDate: "2024-01-02T15:04:05Z"},
+			want:   "2024-01-02T15:04:05Z",
+			wantOK: true,
+		},
+		{
+			name:   "dc:date when pubDate is unreadable",
+			item:   RSSItem{PubDate: "sometime last week", DCDate: "2024-01-02T15:04:05Z", Updated: "2022-01-01"},
+			want:   "2024-01-02T15:04:05Z",
+			wantOK: true,
+		},
+		{
+			name:   "atom updated as last resort",
+			item:   RSSItem{PubDate: "soon", DCDate: " ", Updated: "2024-01-02T15:04:05+00:00"},
+			want:   "2024-01-02T15:04:05Z",
+			wantOK: true,
+		},
+		{
+			name:   "no date at all",
+			item:   RSSItem{},
+			wantOK: true,
+		},
+		{
+			name:   "only unreadable dates",
+			item:   RSSItem{PubDate: "soon", Updated: "later"},
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := itemDate(tt.item)
+			if ok != tt.wantOK {
+				t.Fatalf("itemDate ok = %v, want %v", ok, tt.wantOK)
+			}
+			if tt.want == "" {
+				if !got.IsZero() {
+					t.Errorf("itemDate = %s, want zero time", got)
+				}
+				return
+			}
+			want, _ := time.Parse(time.RFC3339Nano, tt.want)
+			if !got.Equal(want) {
+				t.Errorf("itemDate = %s, want %s", got.UTC().Format(time.RFC3339Nano), tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertAtomKeepsUpdated(t *testing.T) {
+	items := convertAtomToRSSItems([]AtomEntry{{UpdatedAt: "2024-01-02T15:04:05Z"}})
+	got, ok := itemDate(items[0])
+	if !ok || !got.Equal(time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)) {
+		t.Errorf("itemDate of an Atom entry with only <updated> = %s, %v", got, ok)
+	}
+}
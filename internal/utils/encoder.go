@@ -0,0 +1,351 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cyberkillua/dailyread/internal/models"
+)
+
+const (
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+	FormatAtom   = "atom"
+)
+
+// ResponseEncoder writes a payload to the response body in a single format.
+type ResponseEncoder interface {
+	ContentType() string
+	Encode(w io.Writer, payload interface{}) error
+}
+
+// Streaming encoders write directly to the client instead of buffering the
+// whole body, so they cannot change the status code once they have started.
+type streamingEncoder interface {
+	streams() bool
+}
+
+type JSONEncoder struct{}
+
+func (JSONEncoder) ContentType() string { return "application/json" }
+
+func (JSONEncoder) Encode(w io.Writer, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// NDJSONEncoder writes one JSON document per line for each element of a
+// slice payload, flushing as it goes.
+type NDJSONEncoder struct{}
+
+func (NDJSONEncoder) ContentType() string { return "application/x-ndjson" }
+
+func (NDJSONEncoder) streams() bool { return true }
+
+func (NDJSONEncoder) Encode(w io.Writer, payload interface{}) error {
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+
+	v := reflect.ValueOf(payload)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return enc.Encode(payload)
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		if err := enc.Encode(v.Index(i).Interface()); err != nil {
+			return err
+		}
+		if flusher != nil && i%50 == 49 {
+			flusher.Flush()
+		}
+	}
+	return nil
+}
+
+// CSVEncoder writes a slice of structs as CSV, using the json tag of every
+// exported field as the column name.
+type CSVEncoder struct{}
+
+func (CSVEncoder) ContentType() string { return "text/csv; charset=utf-8" }
+
+func (CSVEncoder) Encode(w io.Writer, payload interface{}) error {
+	v := reflect.Indirect(reflect.ValueOf(payload))
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		v = reflect.ValueOf([]interface{}{payload})
+	}
+
+	elemType := v.Type().Elem()
+	for elemType.Kind() == reflect.Ptr || elemType.Kind() == reflect.Interface {
+		if v.Len() == 0 {
+			break
+		}
+		elemType = reflect.Indirect(reflect.ValueOf(v.Index(0).Interface())).Type()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return fmt.Errorf("csv: cannot encode %s", elemType)
+	}
+
+	header, fields := csvColumns(elemType)
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		elem := reflect.Indirect(reflect.ValueOf(v.Index(i).Interface()))
+		record := make([]string, len(fields))
+		for j, field := range fields {
+			record[j] = csvValue(elem.Field(field))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvColumns(t reflect.Type) ([]string, []int) {
+	var header []string
+	var fields []int
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			tagName := strings.Split(tag, ",")[0]
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+		header = append(header, name)
+		fields = append(fields, i)
+	}
+	return header, fields
+}
+
+func csvValue(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	switch value := v.Interface().(type) {
+	case time.Time:
+		if value.IsZero() {
+			return ""
+		}
+		return value.UTC().Format(time.RFC3339)
+	case fmt.Stringer:
+		return value.String()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		data, err := json.Marshal(v.Interface())
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// NewEncoder returns the encoder for a negotiated format. Formats that need
+// extra context, such as Atom, have to be constructed by the caller.
+func NewEncoder(format string) (ResponseEncoder, bool) {
+	switch format {
+	case FormatJSON:
+		return JSONEncoder{}, true
+	case FormatNDJSON:
+		return NDJSONEncoder{}, true
+	case FormatCSV:
+		return CSVEncoder{}, true
+	default:
+		return nil, false
+	}
+}
+
+// AtomEncoder renders a slice of posts as an Atom feed.
+type AtomEncoder struct {
+	Meta FeedMeta
+}
+
+func (AtomEncoder) ContentType() string { return FeedContentType(FeedFormatAtom) }
+
+func (e AtomEncoder) Encode(w io.Writer, payload interface{}) error {
+	posts, ok := payload.([]models.Post)
+	if !ok {
+		return fmt.Errorf("atom: cannot encode %T", payload)
+	}
+	meta := e.Meta
+	for _, post := range posts {
+		if post.UpdatedAt.After(meta.Updated) {
+			meta.Updated = post.UpdatedAt
+		}
+	}
+	if meta.Updated.IsZero() {
+		meta.Updated = time.Now().UTC()
+	}
+
+	data, err := RenderFeed(FeedFormatAtom, meta, posts)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+var formatMediaTypes = map[string][]string{
+	FormatJSON:   {"application/json"},
+	FormatNDJSON: {"application/x-ndjson", "application/ndjson", "application/jsonl"},
+	FormatCSV:    {"text/csv"},
+	FormatAtom:   {"application/atom+xml"},
+}
+
+// NegotiateFormat picks the response format for r out of offers, which are
+// listed in order of preference. A format query parameter overrides the
+// Accept header. The second return value is false when none of the offers
+// is acceptable to the client.
+func NegotiateFormat(r *http.Request, offers ...string) (string, bool) {
+	if len(offers) == 0 {
+		return "", false
+	}
+
+	if format := strings.ToLower(r.URL.Query().Get("format")); format != "" {
+		for _, offer := range offers {
+			if offer == format {
+				return offer, true
+			}
+		}
+		return "", false
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return offers[0], true
+	}
+
+	type acceptRange struct {
+		mediaType string
+		q         float64
+		order     int
+	}
+
+	// refused holds the ranges given q=0, which rule out the formats they
+	// match however broadly another range accepts them.
+	var ranges, refused []acceptRange
+	for i, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if qs, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(qs, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q <= 0 {
+			refused = append(refused, acceptRange{mediaType: mediaType, order: i})
+			continue
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, q: q, order: i})
+	}
+
+	// Highest quality first; among equal quality, more specific ranges win
+	// before falling back to the order the client listed them in.
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].q != ranges[j].q {
+			return ranges[i].q > ranges[j].q
+		}
+		return strings.Count(ranges[i].mediaType, "*") < strings.Count(ranges[j].mediaType, "*")
+	})
+
+	for _, ar := range ranges {
+		for _, offer := range offers {
+			if !mediaTypeMatches(ar.mediaType, formatMediaTypes[offer]) {
+				continue
+			}
+			// A refusal at least as specific as the accepting range wins.
+			isRefused := false
+			for _, rr := range refused {
+				if strings.Count(rr.mediaType, "*") <= strings.Count(ar.mediaType, "*") &&
+					mediaTypeMatches(rr.mediaType, formatMediaTypes[offer]) {
+					isRefused = true
+					break
+				}
+			}
+			if !isRefused {
+				return offer, true
+			}
+		}
+	}
+	return "", false
+}
+
+func mediaTypeMatches(acceptType string, offerTypes []string) bool {
+	for _, offerType := range offerTypes {
+		if acceptType == "*/*" || acceptType == offerType {
+			return true
+		}
+		if strings.HasSuffix(acceptType, "/*") &&
+			strings.HasPrefix(offerType, strings.TrimSuffix(acceptType, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// RespondWithEncoder writes payload using enc. Buffered encoders are fully
+// rendered before the status line is sent so encoding failures still turn
+// into a 500.
+func RespondWithEncoder(w http.ResponseWriter, code int, enc ResponseEncoder, payload interface{}) {
+	if s, ok := enc.(streamingEncoder); ok && s.streams() {
+		w.Header().Set("Content-Type", enc.ContentType())
+		w.WriteHeader(code)
+		if err := enc.Encode(w, payload); err != nil {
+			slog.Error("Error streaming response", "error", err)
+		}
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := enc.Encode(&buf, payload); err != nil {
+		slog.Error("Error encoding response", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", enc.ContentType())
+	w.WriteHeader(code)
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	offers := []string{FormatJSON, FormatNDJSON, FormatCSV, FormatAtom}
+
+	tests := []struct {
+		name   string
+		query  string
+		accept string
+		want   string
+		wantOK bool
+	}{
+		{"no accept header", "", "", FormatJSON, true},
+		{"blank accept header", "", "   ", FormatJSON, true},
+		{"anything", "", "*/*", FormatJSON, true},
+		{"exact type", "", "text/csv", FormatCSV, true},
+		{"type with parameters", "", "text/csv; charset=utf-8", FormatCSV, true},
+		{"case insensitive", "", "Application/Atom+XML", FormatAtom, true},
+		{"ndjson alias", "", "application/jsonl", FormatNDJSON, true},
+		{"subtype wildcard", "", "text/*", FormatCSV, true},
+		{"highest quality wins", "", "application/json;q=0.5, text/csv;q=0.9", FormatCSV, true},
+		{"specific beats wildcard at equal quality", "", "*/*, application/atom+xml", FormatAtom, true},
+		{"client order at equal quality", "", "text/csv, application/x-ndjson", FormatCSV, true},
+		{"browser accept", "", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", FormatJSON, true},
+		{"malformed ranges skipped", "", "garbage;;, text/csv", FormatCSV, true},
+		{"nothing acceptable", "", "text/html, image/png", "", false},
+		{"only refusals", "", "application/json;q=0", "", false},
+		{"refusal beats wildcard", "", "application/json;q=0, */*", FormatNDJSON, true},
+		{"wildcard refusal beats wildcard", "", "application/*;q=0, */*;q=0.5", FormatCSV, true},
+		{"specific accept beats wildcard refusal", "", "text/*;q=0, text/csv", FormatCSV, true},
+		{"query overrides accept", "format=csv", "application/json", FormatCSV, true},
+		{"query is case insensitive", "format=NDJSON", "", FormatNDJSON, true},
+		{"unknown query format", "format=xml", "*/*", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/v1/posts?"+tt.query, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			got, ok := NegotiateFormat(r, offers...)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("NegotiateFormat(%q) = %q, %v, want %q, %v", tt.accept, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNegotiateFormatOfferOrder(t *testing.T) {
+	r := httptest.NewRequest("GET", "/v1/posts", nil)
+	r.Header.Set("Accept", "*/*")
+	if got, _ := NegotiateFormat(r, FormatAtom, FormatJSON); got != FormatAtom {
+		t.Errorf("NegotiateFormat = %q, want the first offer", got)
+	}
+	if _, ok := NegotiateFormat(r); ok {
+		t.Error("NegotiateFormat without offers succeeded")
+	}
+}
+
+func TestCSVEncoder(t *testing.T) {
+	type row struct {
+		Title   string    `json:"title"`
+		URL     *string   `json:"url"`
+		Tags    []string  `json:"tags"`
+		Read    bool      `json:"read"`
+		Created time.Time `json:"created_at"`
+		Secret  string    `json:"-"`
+		Plain   int
+		hidden  int
+	}
+	link := "https://example.com/a"
+
+	var buf bytes.Buffer
+	err := CSVEncoder{}.Encode(&buf, []row{
+		{Title: `Quote "and", comma`, URL: &link, Tags: []string{"go"}, Read: true, Created: time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC), Secret: "x", Plain: 7},
+		{Title: "Empty"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "title,url,tags,read,created_at,Plain\n" +
+		`"Quote ""and"", comma",https://example.com/a,"[""go""]",true,2024-01-02T15:04:05Z,7` + "\n" +
+		"Empty,,null,false,,0\n"
+	if buf.String() != want {
+		t.Errorf("csv =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestCSVEncoderRejectsScalars(t *testing.T) {
+	if err := (CSVEncoder{}).Encode(&bytes.Buffer{}, []int{1, 2}); err == nil {
+		t.Error("encoding a slice of ints succeeded")
+	}
+}
+
+func TestNDJSONEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (NDJSONEncoder{}).Encode(&buf, []map[string]int{{"a": 1}, {"b": 2}}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"a\":1}\n{\"b\":2}\n"; buf.String() != want {
+		t.Errorf("ndjson = %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,218 @@
+package utils
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"github.com/cyberkillua/dailyread/internal/models"
+)
+
+const (
+	FeedFormatRSS  = "rss"
+	FeedFormatAtom = "atom"
+	FeedFormatJSON = "json"
+)
+
+// FeedMeta describes an aggregated output feed.
+type FeedMeta struct {
+	Title       string
+	Description string
+	// HomeURL is the human facing page the feed belongs to.
+	HomeURL string
+	// SelfURL is the canonical URL of the feed document itself.
+	SelfURL string
+	Updated time.Time
+}
+
+type atomOutFeed struct {
+	XMLName xml.Name       `xml:"feed"`
+	Xmlns   string         `xml:"xmlns,attr"`
+	ID      string         `xml:"id"`
+	Title   string         `xml:"title"`
+	Updated string         `xml:"updated"`
+	Links   []atomOutLink  `xml:"link"`
+	Entries []atomOutEntry `xml:"entry"`
+}
+
+type atomOutLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomOutEntry struct {
+	ID        string       `xml:"id"`
+	Title     string       `xml:"title"`
+	Link      atomOutLink  `xml:"link"`
+	Updated   string       `xml:"updated"`
+	Published string       `xml:"published,omitempty"`
+	Author    *atomOutName `xml:"author,omitempty"`
+	Summary   *atomOutText `xml:"summary,omitempty"`
+}
+
+type atomOutName struct {
+	Name string `xml:"name"`
+}
+
+type atomOutText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url,omitempty"`
+	FeedURL     string         `json:"feed_url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url"`
+	Title         string           `json:"title"`
+	ContentHTML   string           `json:"content_html"`
+	ContentText   string           `json:"content_text,omitempty"`
+	DatePublished string           `json:"date_published,omitempty"`
+	DateModified  string           `json:"date_modified,omitempty"`
+	Authors       []jsonFeedAuthor `json:"authors,omitempty"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+// FeedContentType returns the media type served for an output feed format.
+func FeedContentType(format string) string {
+	switch format {
+	case FeedFormatAtom:
+		return "application/atom+xml; charset=utf-8"
+	case FeedFormatJSON:
+		return "application/feed+json; charset=utf-8"
+	default:
+		return "application/rss+xml; charset=utf-8"
+	}
+}
+
+// RenderFeed serializes posts into the requested output feed format.
+func RenderFeed(format string, meta FeedMeta, posts []models.Post) ([]byte, error) {
+	switch format {
+	case FeedFormatRSS:
+		return renderRSS(meta, posts)
+	case FeedFormatAtom:
+		return renderAtom(meta, posts)
+	case FeedFormatJSON:
+		return renderJSONFeed(meta, posts)
+	default:
+		return nil, fmt.Errorf("unknown feed format: %s", format)
+	}
+}
+
+func renderRSS(meta FeedMeta, posts []models.Post) ([]byte, error) {
+	items := make([]RSSItem, len(posts))
+	for i, post := range posts {
+		items[i] = RSSItem{
+			Title:       post.Title,
+			Link:        post.Url,
+			Description: post.DescriptionHTML,
+			PubDate:     postDate(post).Format(time.RFC1123Z),
+			GUID:        post.Url,
+			Source:      post.Postname,
+		}
+	}
+
+	feed := RSS{
+		Version: "2.0",
+		Channel: GenericChannel{
+			Title:         meta.Title,
+			Link:          meta.HomeURL,
+			Description:   meta.Description,
+			LastBuildDate: meta.Updated.UTC().Format(time.RFC1123Z),
+			Items:         items,
+		},
+	}
+
+	return marshalXML(feed)
+}
+
+func renderAtom(meta FeedMeta, posts []models.Post) ([]byte, error) {
+	entries := make([]atomOutEntry, len(posts))
+	for i, post := range posts {
+		entry := atomOutEntry{
+			ID:        "urn:uuid:" + post.ID.String(),
+			Title:     post.Title,
+			Link:      atomOutLink{Rel: "alternate", Type: "text/html", Href: post.Url},
+			Updated:   post.UpdatedAt.UTC().Format(time.RFC3339),
+			Published: postDate(post).Format(time.RFC3339),
+		}
+		if post.Postname != "" {
+			entry.Author = &atomOutName{Name: post.Postname}
+		}
+		if post.DescriptionHTML != "" {
+			entry.Summary = &atomOutText{Type: "html", Body: post.DescriptionHTML}
+		}
+		entries[i] = entry
+	}
+
+	feed := atomOutFeed{
+		Xmlns:   "http://www.w3.org/2005/Atom",
+		ID:      meta.SelfURL,
+		Title:   meta.Title,
+		Updated: meta.Updated.UTC().Format(time.RFC3339),
+		Links: []atomOutLink{
+			{Rel: "self", Type: FeedContentType(FeedFormatAtom), Href: meta.SelfURL},
+			{Rel: "alternate", Type: "text/html", Href: meta.HomeURL},
+		},
+		Entries: entries,
+	}
+
+	return marshalXML(feed)
+}
+
+func renderJSONFeed(meta FeedMeta, posts []models.Post) ([]byte, error) {
+	items := make([]jsonFeedItem, len(posts))
+	for i, post := range posts {
+		item := jsonFeedItem{
+			ID:            post.ID.String(),
+			URL:           post.Url,
+			Title:         post.Title,
+			ContentHTML:   post.DescriptionHTML,
+			ContentText:   post.DescriptionText,
+			DatePublished: postDate(post).Format(time.RFC3339),
+			DateModified:  post.UpdatedAt.UTC().Format(time.RFC3339),
+		}
+		if post.Postname != "" {
+			item.Authors = []jsonFeedAuthor{{Name: post.Postname}}
+		}
+		items[i] = item
+	}
+
+	return json.Marshal(jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       meta.Title,
+		HomePageURL: meta.HomeURL,
+		FeedURL:     meta.SelfURL,
+		Description: meta.Description,
+		Items:       items,
+	})
+}
+
+func marshalXML(v interface{}) ([]byte, error) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// postDate falls back to the time the post was stored when the source feed
+// did not provide a publication date.
+func postDate(post models.Post) time.Time {
+	if post.PublishedAt.IsZero() {
+		return post.CreatedAt.UTC()
+	}
+	return post.PublishedAt.UTC()
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cyberkillua/dailyread/internal/models"
+	"github.com/google/uuid"
+)
+
+func testPost() models.Post {
+	return models.Post{
+		ID:              uuid.MustParse("6f1c2a9e-3b4d-4e5f-8a7b-9c0d1e2f3a4b"),
+		UpdatedAt:       time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+		Title:           "Budget agreed",
+		DescriptionHTML: `<p>Read <a href="https://example.com/minutes">the minutes</a></p>`,
+		DescriptionText: "Read the minutes",
+		Description:     `<p>Read <a href="https://example.com/minutes">the minutes</a></p>`,
+		Url:             "https://example.com/budget",
+		PublishedAt:     time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestRenderFeedUsesDescriptionHTML(t *testing.T) {
+	meta := FeedMeta{Title: "Test", HomeURL: "https://example.com/", SelfURL: "https://dailyread.example/v1/feeds/all.rss"}
+	post := testPost()
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FeedFormatRSS, `<description>&lt;p&gt;Read &lt;a href=&#34;https://example.com/minutes&#34;&gt;the minutes&lt;/a&gt;&lt;/p&gt;</description>`},
+		{FeedFormatAtom, `<summary type="html">&lt;p&gt;Read &lt;a href=&#34;https://example.com/minutes&#34;&gt;the minutes&lt;/a&gt;&lt;/p&gt;</summary>`},
+	}
+	for _, tt := range tests {
+		data, err := RenderFeed(tt.format, meta, []models.Post{post})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if !strings.Contains(string(data), tt.want) {
+			t.Errorf("%s feed is missing %s:\n%s", tt.format, tt.want, data)
+		}
+	}
+
+	data, err := RenderFeed(FeedFormatJSON, meta, []models.Post{post})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var feed struct {
+		HomePageURL string `json:"home_page_url"`
+		Items       []struct {
+			ContentHTML string `json:"content_html"`
+			ContentText string `json:"content_text"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &feed); err != nil {
+		t.Fatal(err)
+	}
+	if feed.HomePageURL != meta.HomeURL {
+		t.Errorf("home_page_url = %q, want %q", feed.HomePageURL, meta.HomeURL)
+	}
+	if len(feed.Items) != 1 || feed.Items[0].ContentHTML != post.DescriptionHTML || feed.Items[0].ContentText != post.DescriptionText {
+		t.Errorf("items = %+v", feed.Items)
+	}
+}
+
+func TestPostJSONAndCSVFields(t *testing.T) {
+	post := testPost()
+
+	data, err := json.Marshal(post)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	json.Unmarshal(data, &fields)
+	for _, key := range []string{"description_html", "description_text", "description"} {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("JSON is missing %q: %s", key, data)
+		}
+	}
+	if fields["description"] != fields["description_html"] {
+		t.Errorf("description = %v, want it to repeat description_html", fields["description"])
+	}
+
+	var buf bytes.Buffer
+	if err := (CSVEncoder{}).Encode(&buf, []models.Post{post}); err != nil {
+		t.Fatal(err)
+	}
+	header := strings.SplitN(buf.String(), "\n", 2)[0]
+	if !strings.Contains(header, "description_html,description_text,description") {
+		t.Errorf("CSV header = %q", header)
+	}
+}
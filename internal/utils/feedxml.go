@@ -0,0 +1,313 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+)
+
+var (
+	utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+	// xmlDeclEncoding captures the encoding named in an XML declaration.
+	xmlDeclEncoding = regexp.MustCompile(`^\s*<\?xml[^>]*encoding=["']([A-Za-z0-9._:-]+)["']`)
+
+	// charRef matches a complete character reference at the start of a slice.
+	charRef = regexp.MustCompile(`^&(?:([A-Za-z][A-Za-z0-9]{1,31})|#[0-9]{1,7}|#[xX][0-9A-Fa-f]{1,6});`)
+
+	cdataStart = []byte("<![CDATA[")
+	cdataEnd   = []byte("]]>")
+)
+
+// maxCharRefLen is the longest reference charRef can match.
+const maxCharRefLen = 34
+
+// declarationPeek is how much of a document is inspected for an XML
+// declaration before decoding starts.
+const declarationPeek = 1024
+
+// xmlEntities are the only named entities XML defines itself.
+var xmlEntities = map[string]bool{"amp": true, "lt": true, "gt": true, "quot": true, "apos": true}
+
+// parseFeed decodes an RSS or Atom document in a single streaming pass.
+// contentType is the response's Content-Type header and is used for the
+// charset when the document does not declare one.
+func parseFeed(r io.Reader, contentType string) (RSS, error) {
+	r, err := newFeedReader(r, contentType)
+	if err != nil {
+		return RSS{}, err
+	}
+
+	decoder := xml.NewDecoder(r)
+	// newFeedReader has already converted the document to UTF-8, whatever
+	// its declaration says.
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	// Feeds are routinely invalid; the non-strict decoder leaves unknown
+	// entities alone instead of failing. AutoClose is deliberately unset:
+	// HTML's void <link> is where RSS keeps the item URL.
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	start, err := firstElement(decoder)
+	if err != nil {
+		return RSS{}, fmt.Errorf("failed to parse XML: %w", err)
+	}
+
+	switch strings.ToLower(start.Name.Local) {
+	case "rss":
+		var rssFeed RSS
+		if err := decoder.DecodeElement(&rssFeed, &start); err != nil {
+			return RSS{}, fmt.Errorf("failed to parse RSS feed: %w", err)
+		}
+		return rssFeed, nil
+	case "feed":
+		var atomFeed Atom
+		if err := decoder.DecodeElement(&atomFeed, &start); err != nil {
+			return RSS{}, fmt.Errorf("failed to parse Atom feed: %w", err)
+		}
+		rssFeed := RSS{
+			Channel: GenericChannel{
+				Title: atomFeed.Title,
+				Link:  alternateLink(atomFeed.Links),
+				Items: convertAtomToRSSItems(atomFeed.Entries),
+			},
+		}
+		return rssFeed, nil
+	default:
+		return RSS{}, fmt.Errorf("unknown feed format: %s", start.Name.Local)
+	}
+}
+
+func firstElement(decoder *xml.Decoder) (xml.StartElement, error) {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if err == io.EOF {
+				return xml.StartElement{}, fmt.Errorf("document has no root element")
+			}
+			return xml.StartElement{}, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start, nil
+		}
+	}
+}
+
+// newFeedReader converts a feed to UTF-8 and repairs the problems that most
+// often make feeds unreadable as it is read: byte order marks, control
+// characters XML forbids, HTML entities, stray ampersands and text that is
+// not in the encoding it claims to be.
+func newFeedReader(r io.Reader, contentType string) (io.Reader, error) {
+	br := bufio.NewReaderSize(r, declarationPeek)
+	head, _ := br.Peek(declarationPeek)
+	if bytes.HasPrefix(head, utf8BOM) {
+		br.Discard(len(utf8BOM))
+		head = head[len(utf8BOM):]
+	}
+
+	label := ""
+	if m := xmlDeclEncoding.FindSubmatch(head); m != nil {
+		label = strings.ToLower(string(m[1]))
+	} else if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		label = strings.ToLower(params["charset"])
+	}
+
+	var text io.Reader
+	switch label {
+	case "", "utf-8", "utf8":
+		text = transform.NewReader(br, lenientUTF8{})
+	default:
+		decoded, err := charset.NewReaderLabel(label, br)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported feed encoding %q: %w", label, err)
+		}
+		text = decoded
+	}
+
+	return transform.NewReader(text, transform.Chain(
+		runes.Remove(runes.Predicate(isForbiddenControl)),
+		&entityRewriter{},
+	)), nil
+}
+
+// isForbiddenControl reports the C0 control characters XML 1.0 does not
+// allow.
+func isForbiddenControl(r rune) bool {
+	return r < 0x20 && r != '\t' && r != '\n' && r != '\r'
+}
+
+// lenientUTF8 passes valid UTF-8 through and reads every byte that is not
+// part of a valid sequence as Windows-1252, the usual culprit when "UTF-8"
+// text does not decode.
+type lenientUTF8 struct{ transform.NopResetter }
+
+func (lenientUTF8) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for nSrc < len(src) {
+		b := src[nSrc]
+		if b < utf8.RuneSelf {
+			if nDst >= len(dst) {
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			dst[nDst] = b
+			nDst++
+			nSrc++
+			continue
+		}
+
+		if !atEOF && !utf8.FullRune(src[nSrc:]) {
+			return nDst, nSrc, transform.ErrShortSrc
+		}
+
+		r, size := utf8.DecodeRune(src[nSrc:])
+		if r == utf8.RuneError && size == 1 {
+			r = charmap.Windows1252.DecodeByte(b)
+			if len(dst)-nDst < utf8.RuneLen(r) {
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			nDst += utf8.EncodeRune(dst[nDst:], r)
+			nSrc++
+			continue
+		}
+
+		if len(dst)-nDst < size {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		nDst += copy(dst[nDst:], src[nSrc:nSrc+size])
+		nSrc += size
+	}
+	return nDst, nSrc, nil
+}
+
+// entityRewriter replaces HTML named entities with numeric references,
+// which every XML parser understands, and escapes ampersands that do not
+// start a reference. CDATA sections are copied unchanged.
+type entityRewriter struct {
+	inCDATA bool
+}
+
+func (t *entityRewriter) Reset() {
+	t.inCDATA = false
+}
+
+func (t *entityRewriter) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	// emit copies out to dst and consumes n bytes of src, or reports that
+	// dst is full.
+	emit := func(out []byte, n int) bool {
+		if len(dst)-nDst < len(out) {
+			err = transform.ErrShortDst
+			return false
+		}
+		nDst += copy(dst[nDst:], out)
+		nSrc += n
+		return true
+	}
+
+	for nSrc < len(src) {
+		rest := src[nSrc:]
+
+		if t.inCDATA {
+			end := bytes.Index(rest, cdataEnd)
+			if end >= 0 {
+				if !emit(rest[:end+len(cdataEnd)], end+len(cdataEnd)) {
+					return nDst, nSrc, err
+				}
+				t.inCDATA = false
+				continue
+			}
+			// Hold back what may be the start of "]]>".
+			n := len(rest)
+			if !atEOF {
+				n -= len(cdataEnd) - 1
+				if n <= 0 {
+					return nDst, nSrc, transform.ErrShortSrc
+				}
+			}
+			if !emit(rest[:n], n) {
+				return nDst, nSrc, err
+			}
+			continue
+		}
+
+		special := bytes.IndexAny(rest, "<&")
+		if special != 0 {
+			n := special
+			if n < 0 {
+				n = len(rest)
+			}
+			if !emit(rest[:n], n) {
+				return nDst, nSrc, err
+			}
+			continue
+		}
+
+		if rest[0] == '<' {
+			if bytes.HasPrefix(rest, cdataStart) {
+				if !emit(cdataStart, len(cdataStart)) {
+					return nDst, nSrc, err
+				}
+				t.inCDATA = true
+				continue
+			}
+			if !atEOF && len(rest) < len(cdataStart) && bytes.HasPrefix(cdataStart, rest) {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+			if !emit(rest[:1], 1) {
+				return nDst, nSrc, err
+			}
+			continue
+		}
+
+		m := charRef.FindSubmatch(rest)
+		if m == nil {
+			if !atEOF && len(rest) < maxCharRefLen {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+			if !emit([]byte("&amp;"), 1) {
+				return nDst, nSrc, err
+			}
+			continue
+		}
+		if !emit(rewriteReference(m), len(m[0])) {
+			return nDst, nSrc, err
+		}
+	}
+	return nDst, nSrc, nil
+}
+
+// rewriteReference returns the replacement for a character reference
+// matched by charRef.
+func rewriteReference(m [][]byte) []byte {
+	name := string(m[1])
+	if name == "" || xmlEntities[name] {
+		return m[0]
+	}
+
+	decoded := html.UnescapeString(string(m[0]))
+	if decoded == string(m[0]) {
+		// Unknown entity: keep it as literal text.
+		return append([]byte("&amp;"), m[0][1:]...)
+	}
+
+	var numeric []byte
+	for _, r := range decoded {
+		numeric = append(numeric, "&#"...)
+		numeric = strconv.AppendInt(numeric, int64(r), 10)
+		numeric = append(numeric, ';')
+	}
+	return numeric
+}
@@ -0,0 +1,251 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestParseFeedCorpus(t *testing.T) {
+	tests := []struct {
+		file        string
+		contentType string
+		title       string
+		itemTitle   string
+		description string
+	}{
+		{
+			file:        "latin1-prolog.xml",
+			title:       "Corpus",
+			itemTitle:   "Café crème",
+			description: "«Bientôt» à Zürich",
+		},
+		{
+			file:        "latin1-header.xml",
+			contentType: "application/rss+xml; charset=ISO-8859-1",
+			title:       "Corpus",
+			itemTitle:   "Café crème",
+			description: "«Bientôt» à Zürich",
+		},
+		{
+			file:        "windows1252-prolog.xml",
+			title:       "Corpus",
+			itemTitle:   "“Smart” quotes – dashes",
+			description: "Costs €10 … it’s fine",
+		},
+		{
+			file:        "windows1252-header.xml",
+			contentType: "text/xml; charset=windows-1252",
+			title:       "Corpus",
+			itemTitle:   "“Smart” quotes – dashes",
+			description: "Costs €10 … it’s fine",
+		},
+		{
+			file:        "bom.xml",
+			contentType: "application/rss+xml; charset=utf-8",
+			title:       "Corpus",
+			itemTitle:   "Byte order mark ✓",
+			description: "Starts with a BOM",
+		},
+		{
+			file:        "control-chars.xml",
+			title:       "Corpus",
+			itemTitle:   "Bell and escape",
+			description: "Verticaltab and formfeed",
+		},
+		{
+			file:        "html-entities.xml",
+			title:       "Corpus",
+			itemTitle:   "News • Today Only",
+			description: "Tom & Jerry … © 2024 &unknownthing; © ☺",
+		},
+		{
+			file:        "bare-ampersand.xml",
+			title:       "Corpus",
+			itemTitle:   "Tom & Jerry",
+			description: "AT&T and R&D; Q&A &",
+		},
+		{
+			// The closing "]]>" straddles byte 4096, the transform buffer size.
+			file:        "cdata.xml",
+			title:       "Corpus",
+			itemTitle:   "Fish & Chips <b>now</b>",
+			description: "<p>Salt & vinegar</p>" + strings.Repeat("x", 3879) + " & end",
+		},
+		{
+			file:        "invalid-utf8.xml",
+			title:       "Corpus",
+			itemTitle:   "Café — valid dash",
+			description: "It’s broken ÿ but readable é",
+		},
+		{
+			file:        "atom-entities.xml",
+			title:       "Atom & friends",
+			itemTitle:   "Olé — Atom",
+			description: "R&D  done",
+		},
+	}
+
+	// Short reads make the transformers see every boundary a slow network
+	// could produce.
+	readers := map[string]func(io.Reader) io.Reader{
+		"whole":    func(r io.Reader) io.Reader { return r },
+		"one byte": iotest.OneByteReader,
+		"half":     iotest.HalfReader,
+	}
+
+	for _, tt := range tests {
+		data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, wrap := range readers {
+			t.Run(tt.file+"/"+name, func(t *testing.T) {
+				feed, err := parseFeed(wrap(bytes.NewReader(data)), tt.contentType)
+				if err != nil {
+					t.Fatalf("parseFeed: %v", err)
+				}
+				if feed.Channel.Title != tt.title {
+					t.Errorf("channel title = %q, want %q", feed.Channel.Title, tt.title)
+				}
+				if len(feed.Channel.Items) != 1 {
+					t.Fatalf("got %d items, want 1", len(feed.Channel.Items))
+				}
+				item := feed.Channel.Items[0]
+				if item.Title != tt.itemTitle {
+					t.Errorf("item title = %q, want %q", item.Title, tt.itemTitle)
+				}
+				if item.Description != tt.description {
+					t.Errorf("item description = %q, want %q", item.Description, tt.description)
+				}
+			})
+		}
+	}
+}
+
+func TestParseFeedAtomLinks(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "atom-entities.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := parseFeed(bytes.NewReader(data), "")
+	if err != nil {
+		t.Fatalf("parseFeed: %v", err)
+	}
+	item := feed.Channel.Items[0]
+	if item.Link != "https://example.com/a?x=1&y=2" {
+		t.Errorf("link = %q", item.Link)
+	}
+	if item.Updated != "2024-01-02T15:04:05Z" {
+		t.Errorf("updated = %q", item.Updated)
+	}
+}
+
+func TestParseFeedChannelLink(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			"rss",
+			`<rss><channel><title>x</title><link>https://example.com/</link></channel></rss>`,
+			"https://example.com/",
+		},
+		{
+			"rss with self link after",
+			`<rss xmlns:atom="http://www.w3.org/2005/Atom"><channel><link>https://example.com/</link><atom:link href="https://example.com/feed" rel="self"/></channel></rss>`,
+			"https://example.com/",
+		},
+		{
+			"rss with self link before",
+			`<rss xmlns:atom="http://www.w3.org/2005/Atom"><channel><atom:link href="https://example.com/feed" rel="self"/><link>https://example.com/</link></channel></rss>`,
+			"https://example.com/",
+		},
+		{
+			"atom",
+			`<feed xmlns="http://www.w3.org/2005/Atom"><link rel="self" href="https://example.com/feed"/><link rel="alternate" type="text/html" href="https://example.com/"/></feed>`,
+			"https://example.com/",
+		},
+		{
+			"atom without rel",
+			`<feed xmlns="http://www.w3.org/2005/Atom"><link href="https://example.com/"/></feed>`,
+			"https://example.com/",
+		},
+		{
+			"atom with only a self link",
+			`<feed xmlns="http://www.w3.org/2005/Atom"><link rel="self" href="https://example.com/feed"/></feed>`,
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := parseFeed(strings.NewReader(tt.body), "")
+			if err != nil {
+				t.Fatalf("parseFeed: %v", err)
+			}
+			if feed.Channel.Link != tt.want {
+				t.Errorf("channel link = %q, want %q", feed.Channel.Link, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFeedRejects(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+	}{
+		{"empty", "", ""},
+		{"html page", "<!DOCTYPE html><html><body>Not a feed</body></html>", "text/html"},
+		{"unknown encoding", `<?xml version="1.0" encoding="x-no-such-charset"?><rss/>`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseFeed(strings.NewReader(tt.body), tt.contentType); err == nil {
+				t.Error("parseFeed succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestEntityRewriter(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"a &amp; b", "a &amp; b"},
+		{"a & b", "a &amp; b"},
+		{"&bull;", "&#8226;"},
+		{"&nbsp;x", "&#160;x"},
+		{"&nosuchentity;", "&amp;nosuchentity;"},
+		{"&#169; &#xA9;", "&#169; &#xA9;"},
+		{"trailing &", "trailing &amp;"},
+		{"<![CDATA[a & b &bull; ]] ]]> & c", "<![CDATA[a & b &bull; ]] ]]> &amp; c"},
+		{"<![CDATA[unterminated & ]]", "<![CDATA[unterminated & ]]"},
+		{"<b>&lt;</b>", "<b>&lt;</b>"},
+	}
+	for _, tt := range tests {
+		for _, wrap := range []func(io.Reader) io.Reader{
+			func(r io.Reader) io.Reader { return r },
+			iotest.OneByteReader,
+		} {
+			r, err := newFeedReader(wrap(strings.NewReader(tt.in)), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("%q: %v", tt.in, err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("rewrite(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		}
+	}
+}
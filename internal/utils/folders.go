@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/cyberkillua/dailyread/internal/database"
+)
+
+// FolderPath returns the names from the outermost folder down to id.
+func FolderPath(folders map[uuid.UUID]database.Folder, id uuid.UUID) []string {
+	var path []string
+	// The depth limit guards against a cycle written behind our back.
+	for depth := 0; depth <= len(folders); depth++ {
+		folder, ok := folders[id]
+		if !ok {
+			break
+		}
+		path = append([]string{folder.Name}, path...)
+		if !folder.ParentID.Valid {
+			break
+		}
+		id = folder.ParentID.UUID
+	}
+	return path
+}
+
+// EnsureFolderPath returns the innermost folder of path, creating any
+// folder along it that does not exist yet. Names match case-insensitively.
+func EnsureFolderPath(ctx context.Context, db *database.Queries, path []string) (database.Folder, error) {
+	var folder database.Folder
+	parent := uuid.NullUUID{}
+	for _, name := range path {
+		var err error
+		folder, err = db.GetFolderByName(ctx, database.GetFolderByNameParams{
+			ParentID: parent,
+			Name:     name,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			now := time.Now().UTC()
+			folder, err = db.CreateFolder(ctx, database.CreateFolderParams{
+				ID:        uuid.New(),
+				CreatedAt: now,
+				UpdatedAt: now,
+				Name:      name,
+				ParentID:  parent,
+			})
+		}
+		if err != nil {
+			return database.Folder{}, err
+		}
+		parent = uuid.NullUUID{UUID: folder.ID, Valid: true}
+	}
+	return folder, nil
+}
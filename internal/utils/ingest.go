@@ -0,0 +1,188 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/cyberkillua/dailyread/internal/database"
+	"github.com/cyberkillua/dailyread/internal/fetcher"
+	"github.com/cyberkillua/dailyread/internal/models"
+)
+
+// Values stored in webpages.last_fetch_status.
+const (
+	FetchStatusOK         = "ok"
+	FetchStatusFetchError = "fetch_error"
+	FetchStatusStoreError = "store_error"
+	// FetchStatusBlocked means robots.txt does not allow fetching the feed.
+	FetchStatusBlocked = "robots_disallowed"
+)
+
+// ingestBatchSize caps the number of rows sent in one multi-row upsert.
+const ingestBatchSize = 500
+
+type ingestItem struct {
+	Title string
+	// Description is sanitized HTML and DescriptionText its plain text.
+	Description     string
+	DescriptionText string
+	Link            string
+	// PublishedAt is zero when the feed did not provide a date.
+	PublishedAt time.Time
+	Author      string
+	Categories  []string
+	Enclosures  []models.Enclosure
+}
+
+// ingest upserts items, records the successful fetch and follows a
+// permanent redirect inside a single transaction. movedTo is the URL the
+// fetch was permanently redirected to, if any, and siteURL the website the
+// feed names, if any.
+func (s *Scraper) ingest(ctx context.Context, page database.Webpage, items []ingestItem, movedTo, siteURL string, result *FeedResult) error {
+	tx, err := s.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	qtx := s.DB.WithTx(tx)
+	now := time.Now().UTC()
+
+	pageName := sql.NullString{}
+	if page.Name != "" {
+		pageName = sql.NullString{String: page.Name, Valid: true}
+	}
+
+	var created, updated int
+	var queued []uuid.UUID
+	for start := 0; start < len(items); start += ingestBatchSize {
+		batch := items[start:min(start+ingestBatchSize, len(items))]
+
+		params := database.UpsertPostsParams{
+			Ids:              make([]uuid.UUID, len(batch)),
+			Now:              now,
+			Titles:           make([]string, len(batch)),
+			Descriptions:     make([]string, len(batch)),
+			DescriptionTexts: make([]string, len(batch)),
+			Authors:          make([]string, len(batch)),
+			Categories:       make([]string, len(batch)),
+			Enclosures:       make([]string, len(batch)),
+			Urls:             make([]string, len(batch)),
+			PublishedAts:     make([]string, len(batch)),
+			Postname:         pageName,
+			WebpageID:        page.ID,
+		}
+		for i, item := range batch {
+			params.Ids[i] = uuid.New()
+			params.Titles[i] = item.Title
+			params.Descriptions[i] = item.Description
+			params.DescriptionTexts[i] = item.DescriptionText
+			params.Authors[i] = item.Author
+			params.Categories[i] = jsonArray(item.Categories)
+			params.Enclosures[i] = jsonArray(item.Enclosures)
+			params.Urls[i] = item.Link
+			if !item.PublishedAt.IsZero() {
+				params.PublishedAts[i] = item.PublishedAt.UTC().Format("2006-01-02 15:04:05.999999")
+			}
+		}
+
+		rows, err := qtx.UpsertPosts(ctx, params)
+		if err != nil {
+			return fmt.Errorf("upserting posts: %w", err)
+		}
+		for _, row := range rows {
+			if row.Inserted {
+				created++
+				queued = append(queued, row.ID)
+			} else {
+				updated++
+			}
+		}
+	}
+
+	// New posts of a full text webpage wait for the content worker.
+	if page.FullText && len(queued) > 0 {
+		err = qtx.QueuePostContents(ctx, database.QueuePostContentsParams{
+			PostIds: queued,
+			Now:     now,
+		})
+		if err != nil {
+			return fmt.Errorf("queueing full text: %w", err)
+		}
+	}
+
+	err = qtx.RecordWebpageFetch(ctx, database.RecordWebpageFetchParams{
+		FetchedAt: now,
+		Status:    FetchStatusOK,
+		SiteUrl:   sql.NullString{String: siteURL, Valid: siteURL != ""},
+		ID:        page.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("recording fetch: %w", err)
+	}
+
+	moved, err := followPermanentRedirect(ctx, qtx, page, movedTo, now)
+	if err != nil {
+		return fmt.Errorf("recording redirect: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+
+	if moved {
+		result.MovedTo = movedTo
+	}
+	result.Created += created
+	result.Updated += updated
+	result.Duplicates += len(items) - created - updated
+	return nil
+}
+
+// jsonArray encodes values for a JSONB array column, writing [] rather than
+// null for no values.
+func jsonArray[T any](values []T) string {
+	if len(values) == 0 {
+		return "[]"
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
+// recordFailure marks the webpage as fetched with the error that stopped
+// the scrape, so a broken feed is not retried on every cycle. When the host
+// asked us to back off, the webpage is not claimed again until then.
+func (s *Scraper) recordFailure(ctx context.Context, page database.Webpage, status string, cause error) {
+	now := time.Now().UTC()
+
+	backoffUntil := sql.NullTime{}
+	var statusErr *fetcher.HTTPStatusError
+	var hostErr *fetcher.HostBackoffError
+	switch {
+	case errors.As(cause, &statusErr) && statusErr.RetryAfter > 0:
+		backoffUntil = sql.NullTime{Time: now.Add(statusErr.RetryAfter), Valid: true}
+	case errors.As(cause, &hostErr):
+		backoffUntil = sql.NullTime{Time: hostErr.Until.UTC(), Valid: true}
+	}
+
+	err := s.DB.RecordWebpageFetch(ctx, database.RecordWebpageFetchParams{
+		FetchedAt:    now,
+		Status:       status,
+		Error:        sql.NullString{String: cause.Error(), Valid: true},
+		BackoffUntil: backoffUntil,
+		ID:           page.ID,
+	})
+	if err != nil {
+		slog.Error("Error recording failed fetch", "webpage_id", page.ID, "error", err)
+	}
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/cyberkillua/dailyread/internal/models"
+)
+
+// itemAuthor prefers dc:creator, which holds a name, over RSS author,
+// which holds an email address optionally followed by a name in
+// parentheses, e.g. "jane@example.com (Jane Doe)".
+func itemAuthor(item RSSItem) string {
+	if creator := strings.TrimSpace(item.DCCreator); creator != "" {
+		return creator
+	}
+	author := strings.TrimSpace(item.Author)
+	if open := strings.Index(author, "("); open >= 0 && strings.HasSuffix(author, ")") {
+		if name := strings.TrimSpace(author[open+1 : len(author)-1]); name != "" {
+			return name
+		}
+	}
+	return author
+}
+
+// itemCategories returns the item's categories without blanks or
+// duplicates, in feed order.
+func itemCategories(item RSSItem) []string {
+	var categories []string
+	seen := make(map[string]bool)
+	for _, category := range item.Categories {
+		category = strings.TrimSpace(category)
+		if category == "" || seen[strings.ToLower(category)] {
+			continue
+		}
+		seen[strings.ToLower(category)] = true
+		categories = append(categories, category)
+	}
+	return categories
+}
+
+// itemEnclosures returns the item's enclosures that have an absolute http
+// or https URL, resolving relative ones against the item link.
+func itemEnclosures(item RSSItem) []models.Enclosure {
+	base, _ := url.Parse(item.Link)
+
+	var enclosures []models.Enclosure
+	for _, enclosure := range item.Enclosures {
+		u, err := url.Parse(strings.TrimSpace(enclosure.URL))
+		if err != nil || enclosure.URL == "" {
+			continue
+		}
+		if base != nil {
+			u = base.ResolveReference(u)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			continue
+		}
+
+		length, _ := strconv.ParseInt(strings.TrimSpace(enclosure.Length), 10, 64)
+		enclosures = append(enclosures, models.Enclosure{
+			URL:    u.String(),
+			Type:   strings.TrimSpace(enclosure.Type),
+			Length: max(length, 0),
+		})
+	}
+	return enclosures
+}
@@ -0,0 +1,171 @@
+package utils
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/cyberkillua/dailyread/internal/database"
+)
+
+type OPML struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    OPMLHead `xml:"head"`
+	Body    OPMLBody `xml:"body"`
+}
+
+type OPMLHead struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type OPMLBody struct {
+	Outlines []OPMLOutline `xml:"outline"`
+}
+
+// OPMLOutline is either a subscription, when XMLURL is set, or a folder
+// holding further outlines.
+type OPMLOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string        `xml:"htmlUrl,attr,omitempty"`
+	Outlines []OPMLOutline `xml:"outline"`
+}
+
+// OPMLFeed is a subscription found in an OPML document together with the
+// folders it was nested in, outermost first.
+type OPMLFeed struct {
+	Name    string
+	URL     string
+	HTMLURL string
+	Folders []string
+}
+
+func ParseOPML(r io.Reader) (OPML, error) {
+	var doc OPML
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	if err := decoder.Decode(&doc); err != nil {
+		return OPML{}, fmt.Errorf("failed to parse OPML: %w", err)
+	}
+	return doc, nil
+}
+
+// Feeds flattens the outline tree into the subscriptions it contains.
+func (o OPML) Feeds() []OPMLFeed {
+	var feeds []OPMLFeed
+	var walk func(outlines []OPMLOutline, folders []string)
+	walk = func(outlines []OPMLOutline, folders []string) {
+		for _, outline := range outlines {
+			name := strings.TrimSpace(outline.Title)
+			if name == "" {
+				name = strings.TrimSpace(outline.Text)
+			}
+
+			if outline.XMLURL != "" {
+				if name == "" {
+					name = outline.XMLURL
+				}
+				feeds = append(feeds, OPMLFeed{
+					Name:    name,
+					URL:     strings.TrimSpace(outline.XMLURL),
+					HTMLURL: outline.HTMLURL,
+					Folders: append([]string(nil), folders...),
+				})
+			}
+
+			if len(outline.Outlines) > 0 {
+				walk(outline.Outlines, append(folders, name))
+			}
+		}
+	}
+	walk(o.Body.Outlines, nil)
+	return feeds
+}
+
+// BuildOPML nests webpages under their folders. A webpage in several
+// folders is listed in each of them, and one in none sits at the top level,
+// so importing the document restores the same structure.
+func BuildOPML(title string, webpages []database.Webpage, folders []database.Folder, memberships []database.WebpageFolder) OPML {
+	children := make(map[uuid.UUID][]database.Folder)
+	var roots []database.Folder
+	for _, folder := range folders {
+		if folder.ParentID.Valid {
+			children[folder.ParentID.UUID] = append(children[folder.ParentID.UUID], folder)
+		} else {
+			roots = append(roots, folder)
+		}
+	}
+
+	byID := make(map[uuid.UUID]database.Webpage, len(webpages))
+	for _, webpage := range webpages {
+		byID[webpage.ID] = webpage
+	}
+	inFolder := make(map[uuid.UUID][]database.Webpage)
+	foldered := make(map[uuid.UUID]bool)
+	for _, m := range memberships {
+		if webpage, ok := byID[m.WebpageID]; ok {
+			inFolder[m.FolderID] = append(inFolder[m.FolderID], webpage)
+			foldered[m.WebpageID] = true
+		}
+	}
+
+	var folderOutline func(folder database.Folder) OPMLOutline
+	folderOutline = func(folder database.Folder) OPMLOutline {
+		outline := OPMLOutline{Text: folder.Name, Title: folder.Name}
+		for _, child := range children[folder.ID] {
+			outline.Outlines = append(outline.Outlines, folderOutline(child))
+		}
+		for _, webpage := range inFolder[folder.ID] {
+			outline.Outlines = append(outline.Outlines, webpageOutline(webpage))
+		}
+		return outline
+	}
+
+	doc := OPML{
+		Version: "2.0",
+		Head: OPMLHead{
+			Title:       title,
+			DateCreated: time.Now().UTC().Format(time.RFC1123Z),
+		},
+	}
+	for _, folder := range roots {
+		doc.Body.Outlines = append(doc.Body.Outlines, folderOutline(folder))
+	}
+	for _, webpage := range webpages {
+		if !foldered[webpage.ID] {
+			doc.Body.Outlines = append(doc.Body.Outlines, webpageOutline(webpage))
+		}
+	}
+	return doc
+}
+
+func webpageOutline(webpage database.Webpage) OPMLOutline {
+	return OPMLOutline{
+		Text:   webpage.Name,
+		Title:  webpage.Name,
+		Type:   "rss",
+		XMLURL: webpage.Url,
+	}
+}
+
+// WriteOPML writes doc as an indented XML document.
+func WriteOPML(w io.Writer, doc OPML) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/cyberkillua/dailyread/internal/database"
+)
+
+// permanentRedirectThreshold is the number of consecutive fetches that must
+// be permanently redirected to the same URL before the webpage's URL is
+// updated, so one misconfigured response does not move a feed.
+const permanentRedirectThreshold = 3
+
+// followPermanentRedirect counts consecutive permanent redirects of page to
+// movedTo and, once there are enough, moves the webpage to the new URL. The
+// old URL is kept as an alias so the feed cannot be added a second time
+// under it. It reports whether the webpage was moved.
+func followPermanentRedirect(ctx context.Context, qtx *database.Queries, page database.Webpage, movedTo string, now time.Time) (bool, error) {
+	if movedTo == page.Url || !ValidFeedURL(movedTo) {
+		movedTo = ""
+	}
+
+	count, err := qtx.RecordWebpageRedirect(ctx, database.RecordWebpageRedirectParams{
+		RedirectUrl: sql.NullString{String: movedTo, Valid: movedTo != ""},
+		ID:          page.ID,
+	})
+	if err != nil || movedTo == "" || count < permanentRedirectThreshold {
+		return false, err
+	}
+
+	other, err := qtx.GetWebpageByURLOrAlias(ctx, movedTo)
+	switch {
+	case err == nil && other.ID != page.ID:
+		slog.Warn("Not following permanent redirect to a URL another webpage uses",
+			"webpage_id", page.ID,
+			"url", page.Url,
+			"moved_to", movedTo,
+			"other_webpage_id", other.ID,
+		)
+		return false, nil
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		return false, err
+	}
+
+	// The new URL may itself be an old alias of this webpage.
+	if err := qtx.DeleteWebpageURLAlias(ctx, movedTo); err != nil {
+		return false, err
+	}
+	err = qtx.CreateWebpageURLAlias(ctx, database.CreateWebpageURLAliasParams{
+		Url:       page.Url,
+		WebpageID: page.ID,
+		CreatedAt: now,
+	})
+	if err != nil {
+		return false, err
+	}
+	err = qtx.MoveWebpageURL(ctx, database.MoveWebpageURLParams{
+		Url:       movedTo,
+		UpdatedAt: now,
+		ID:        page.ID,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	slog.Info("Feed moved permanently", "webpage_id", page.ID, "from", page.Url, "to", movedTo)
+	return true, nil
+}
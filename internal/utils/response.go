@@ -1,31 +1,9 @@
 package utils
 
 import (
-	"encoding/json"
-	"log"
 	"net/http"
 )
 
-func RespondWithError(w http.ResponseWriter, code int, message string) {
-	if code > 499 {
-		log.Printf("Responding with error: %v", message)
-	}
-
-	type errorResponse struct {
-		Error string `json:"error"`
-	}
-	RespondWithJSON(w, code, errorResponse{message})
-}
-
 func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
-
-	data, err := json.Marshal(payload)
-	if err != nil {
-		log.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	w.Write(data)
+	RespondWithEncoder(w, code, JSONEncoder{}, payload)
 }
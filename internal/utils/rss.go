@@ -1,32 +1,38 @@
 package utils
 
 import (
-	"bytes"
+	"context"
 	"encoding/xml"
-	"fmt"
-	"io"
-	"log"
-	"net/http"
-	"regexp"
-	"strings"
-	"time"
+
+	"github.com/cyberkillua/dailyread/internal/fetcher"
 )
 
 type Atom struct {
 	XMLName xml.Name    `xml:"feed"`
 	Title   string      `xml:"title"`
+	Links   []AtomLink  `xml:"link"`
 	Entries []AtomEntry `xml:"entry"`
 }
 
 type AtomEntry struct {
-	Title string `xml:"title"`
-	Link  struct {
-		Rel  string `xml:"rel,attr"`
-		Type string `xml:"type,attr"`
-		Href string `xml:"href,attr"`
-	} `xml:"link"`
-	Description string `xml:"summary"`
-	PublishedAt string `xml:"published"`
+	Title       string     `xml:"title"`
+	Links       []AtomLink `xml:"link"`
+	Description string     `xml:"summary"`
+	PublishedAt string     `xml:"published"`
+	UpdatedAt   string     `xml:"updated"`
+	Authors     []struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+	Categories []struct {
+		Term string `xml:"term,attr"`
+	} `xml:"category"`
+}
+
+type AtomLink struct {
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Href   string `xml:"href,attr"`
+	Length string `xml:"length,attr"`
 }
 
 type RSS struct {
@@ -35,11 +41,15 @@ type RSS struct {
 	Channel GenericChannel `xml:"channel,omitempty"`
 }
 type GenericChannel struct {
-	Title       string    `xml:"title,omitempty"`
-	Link        string    `xml:"link,omitempty"`
-	Description string    `xml:"description,omitempty"`
-	Language    string    `xml:"language,omitempty"`
-	Items       []RSSItem `xml:"item,omitempty"`
+	Title string `xml:"title,omitempty"`
+	// AtomLinks takes the <atom:link rel="self"> many RSS feeds carry, which
+	// would otherwise overwrite Link. It is never written.
+	AtomLinks     []AtomLink `xml:"http://www.w3.org/2005/Atom link,omitempty"`
+	Link          string     `xml:"link,omitempty"`
+	Description   string     `xml:"description,omitempty"`
+	Language      string     `xml:"language,omitempty"`
+	LastBuildDate string     `xml:"lastBuildDate,omitempty"`
+	Items         []RSSItem  `xml:"item,omitempty"`
 }
 
 type RSSItem struct {
@@ -47,127 +57,86 @@ type RSSItem struct {
 	Link        string `xml:"link"`
 	Description string `xml:"description,omitempty"`
 	PubDate     string `xml:"pubDate,omitempty"`
+	GUID        string `xml:"guid,omitempty"`
+	Source      string `xml:"source,omitempty"`
+	// DCDate and Updated are read as fallbacks when pubDate is missing or
+	// unparseable; they are never written.
+	DCDate  string `xml:"http://purl.org/dc/elements/1.1/ date,omitempty"`
+	Updated string `xml:"http://www.w3.org/2005/Atom updated,omitempty"`
+	// Author is usually an email address; DCCreator, when present, is the
+	// author's name.
+	Author     string         `xml:"author,omitempty"`
+	DCCreator  string         `xml:"http://purl.org/dc/elements/1.1/ creator,omitempty"`
+	Categories []string       `xml:"category,omitempty"`
+	Enclosures []RSSEnclosure `xml:"enclosure,omitempty"`
+}
+
+type RSSEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length string `xml:"length,attr,omitempty"`
+}
+
+// alternateLink returns the page an Atom feed belongs to, or "" if it
+// names none.
+func alternateLink(links []AtomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return link.Href
+		}
+	}
+	return ""
 }
 
 func convertAtomToRSSItems(entries []AtomEntry) []RSSItem {
 	rssItems := make([]RSSItem, len(entries))
 	for i, entry := range entries {
-		rssItems[i] = RSSItem{
+		item := RSSItem{
 			Title:       entry.Title,
-			Link:        entry.Link.Href,
 			Description: entry.Description,
 			PubDate:     entry.PublishedAt, // Atom dates are ISO 8601; RSS uses RFC 1123
+			Updated:     entry.UpdatedAt,
 		}
-	}
-	return rssItems
-}
-
-func urlToRSS(url string) (RSS, error) {
-	// Create a custom transport to handle redirects more explicitly
-	transport := &http.Transport{
-		MaxIdleConns:       10,
-		IdleConnTimeout:    30 * time.Second,
-		DisableCompression: true,
-	}
-
-	httpClient := http.Client{
-		Timeout:   10 * time.Second,
-		Transport: transport,
-		// Explicitly handle redirects to get more information
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			if len(via) >= 5 {
-				return fmt.Errorf("stopped after 5 redirects")
+		for _, link := range entry.Links {
+			switch link.Rel {
+			case "", "alternate":
+				if item.Link == "" {
+					item.Link = link.Href
+				}
+			case "enclosure":
+				item.Enclosures = append(item.Enclosures, RSSEnclosure{URL: link.Href, Type: link.Type, Length: link.Length})
 			}
-
-			if len(via) > 0 {
-				log.Printf("Redirect from %s to %s", via[len(via)-1].URL, req.URL)
+		}
+		// Without an alternate link, any link that is not an enclosure
+		// is the best guess at the entry's page.
+		for _, link := range entry.Links {
+			if item.Link == "" && link.Rel != "enclosure" {
+				item.Link = link.Href
 			}
-			return nil
-		},
-	}
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return RSS{}, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	userAgents := []string{
-		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36",
-		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36",
-		"Feedfetcher-Google; (+http://www.google.com/feedfetcher.html)",
-	}
-
-	req.Header.Set("User-Agent", userAgents[0])
-	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml, text/xml")
-	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
-
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return RSS{}, fmt.Errorf("failed to fetch feed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Extensive logging for debugging
-	log.Printf("Request URL: %s", url)
-	log.Printf("Response Status: %s", resp.Status)
-	log.Printf("Content-Type: %s", resp.Header.Get("Content-Type"))
-
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return RSS{}, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	// Debug: log first 500 characters
-	// log.Printf("Response Body (first 500 chars): %s", string(data[:min(len(data), 500)]))
-
-	processedData := preprocessXML(data)
-
-	var root struct {
-		XMLName xml.Name
-	}
-	if err := xml.Unmarshal(processedData, &root); err != nil {
-		return RSS{}, fmt.Errorf("failed to parse XML: %w", err)
-	}
-
-	switch strings.ToLower(root.XMLName.Local) {
-	case "rss":
-		var rssFeed RSS
-		if err := xml.Unmarshal(processedData, &rssFeed); err != nil {
-			return RSS{}, fmt.Errorf("failed to parse RSS feed: %w", err)
 		}
-		return rssFeed, nil
-	case "feed":
-		var atomFeed Atom
-		if err := xml.Unmarshal(processedData, &atomFeed); err != nil {
-			return RSS{}, fmt.Errorf("failed to parse Atom feed: %w", err)
+		if len(entry.Authors) > 0 {
+			item.DCCreator = entry.Authors[0].Name
 		}
-		rssFeed := RSS{
-			Channel: GenericChannel{
-				Title: atomFeed.Title,
-				Items: convertAtomToRSSItems(atomFeed.Entries),
-			},
+		for _, category := range entry.Categories {
+			item.Categories = append(item.Categories, category.Term)
 		}
-		return rssFeed, nil
-	default:
-		return RSS{}, fmt.Errorf("unknown feed format: %s", root.XMLName.Local)
+		rssItems[i] = item
 	}
+	return rssItems
 }
 
-func preprocessXML(data []byte) []byte {
+// feedAccept is the Accept header sent with feed requests.
+const feedAccept = "application/rss+xml, application/atom+xml, application/xml;q=0.9, text/xml;q=0.9, */*;q=0.8"
 
-	data = bytes.TrimSpace(data)
-	data = regexp.MustCompile(`<!--.*?-->`).ReplaceAll(data, []byte{})
-
-	// Replace problematic character entities
-	replacements := []struct{ old, new []byte }{
-		{[]byte("&bull;"), []byte("&#8226;")},
-		{[]byte("&nbsp;"), []byte(" ")},
-		// Add more entity replacements as needed
-	}
-
-	for _, r := range replacements {
-		data = bytes.ReplaceAll(data, r.old, r.new)
+// urlToRSS fetches and parses a feed. movedTo is the URL the feed has
+// permanently moved to, or empty when it has not moved.
+func urlToRSS(ctx context.Context, f *fetcher.Fetcher, url, userAgent string) (feed RSS, movedTo string, err error) {
+	resp, err := f.Get(ctx, fetcher.Request{URL: url, Accept: feedAccept, UserAgent: userAgent})
+	if err != nil {
+		return RSS{}, "", err
 	}
+	defer resp.Body.Close()
 
-	return data
+	feed, err = parseFeed(resp.Body, resp.Header.Get("Content-Type"))
+	return feed, resp.PermanentURL, err
 }
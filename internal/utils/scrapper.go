@@ -3,138 +3,439 @@ package utils
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/url"
+	"os"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/cyberkillua/dailyread/internal/cluster"
+	"github.com/cyberkillua/dailyread/internal/content"
 	"github.com/cyberkillua/dailyread/internal/database"
+	"github.com/cyberkillua/dailyread/internal/fetcher"
+	"github.com/cyberkillua/dailyread/internal/health"
+	"github.com/cyberkillua/dailyread/internal/metrics"
 	"github.com/google/uuid"
 )
 
-func StartScrapping(db *database.Queries, concurrency int, durationBetween time.Duration) {
-	log.Printf("Scarping on %v goroutines every %v", concurrency, durationBetween)
+// heartbeatInterval is how often the scraper reports that it is alive while
+// it is idle or waiting for a cycle to finish.
+const heartbeatInterval = 30 * time.Second
 
-	ticker := time.NewTicker(durationBetween)
-	for ; ; <-ticker.C {
-		pages, err := db.GetNextWebpageToFetch(context.Background(), int32(concurrency))
+// Scraper claims webpages that are due and ingests their feeds. Several
+// scrapers, in one or many processes, can share the same database: each
+// webpage is leased to a single worker while it is being fetched, and a
+// lease left behind by a crashed worker simply expires.
+type Scraper struct {
+	Conn      *sql.DB
+	DB        *database.Queries
+	Heartbeat *health.Heartbeat
+	// WorkerID identifies this scraper as the owner of its leases.
+	WorkerID string
+	// Concurrency is the number of webpages claimed and fetched per cycle.
+	Concurrency int
+	// Interval is the time between cycles.
+	Interval time.Duration
+	// LeaseDuration is how long a claimed webpage stays reserved for this
+	// worker; it must comfortably exceed the time needed to ingest a feed.
+	LeaseDuration time.Duration
+	// MaxItemAge drops items published longer ago than this, unless the
+	// webpage sets its own cutoff. Zero keeps items of any age.
+	MaxItemAge time.Duration
+	// Fetcher is shared by every fetch so connections are reused.
+	Fetcher *fetcher.Fetcher
+	// Clusterer groups the posts each cycle created with earlier coverage
+	// of the same story. Nil skips clustering.
+	Clusterer *cluster.Clusterer
+}
 
-		if err != nil {
-			log.Printf("Error getting feeds to scrap: %v", err)
-			continue
-		}
-		wg := &sync.WaitGroup{}
+// FeedResult summarises a single scrape of one webpage.
+type FeedResult struct {
+	Webpage    database.Webpage
+	Found      int
+	Created    int
+	Updated    int
+	Duplicates int
+	Skipped    int
+	// MovedTo is the webpage's new URL when this scrape updated it after
+	// repeated permanent redirects.
+	MovedTo  string
+	Duration time.Duration
+	Err      error
+}
+
+func NewScraper(conn *sql.DB, concurrency int, interval time.Duration) *Scraper {
+	return &Scraper{
+		Conn:          conn,
+		DB:            database.New(conn),
+		WorkerID:      DefaultWorkerID(),
+		Concurrency:   concurrency,
+		Interval:      interval,
+		LeaseDuration: 10 * time.Minute,
+		MaxItemAge:    60 * 24 * time.Hour,
+		Fetcher:       fetcher.New(fetcher.Options{}),
+		Clusterer:     cluster.New(conn, 48*time.Hour),
+	}
+}
+
+// DefaultWorkerID combines the host name and process id so leases can be
+// traced back to the process holding them.
+func DefaultWorkerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.NewString()[:8])
+}
+
+// StartScrapping runs a cycle immediately and then every Interval until ctx
+// is cancelled.
+func (s *Scraper) StartScrapping(ctx context.Context) {
+	slog.Info("Starting scraper",
+		"worker_id", s.WorkerID,
+		"concurrency", s.Concurrency,
+		"interval", s.Interval,
+		"lease", s.LeaseDuration,
+	)
+	s.Heartbeat.Beat()
+
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	beat := time.NewTicker(heartbeatInterval)
+	defer beat.Stop()
 
-		for _, page := range pages {
-			wg.Add(1)
+	scheduled := time.Now()
+	for {
+		s.scrapeCycle(ctx, beat.C, scheduled)
 
-			go scrapeFeed(db, wg, page)
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				slog.Info("Stopping scraper", "worker_id", s.WorkerID)
+				return
+			case scheduled = <-ticker.C:
+				break wait
+			case <-beat.C:
+				s.Heartbeat.Beat()
+			}
 		}
-		wg.Wait()
 	}
 }
 
-func scrapeFeed(db *database.Queries, wg *sync.WaitGroup, page database.Webpage) {
-	defer wg.Done()
+func (s *Scraper) scrapeCycle(ctx context.Context, beat <-chan time.Time, scheduled time.Time) {
+	cycleStart := time.Now()
+	metrics.SchedulerLag.Set(cycleStart.Sub(scheduled).Seconds())
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.ScrapeOnce(ctx)
+		done <- err
+	}()
 
-	_, err := db.MarkWebpageAsFetched(context.Background(), page.ID)
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				slog.Error("Error getting feeds to scrape", "error", err)
+				s.Heartbeat.CycleFailed(err)
+				return
+			}
+			s.Heartbeat.CycleSucceeded()
+			metrics.ScrapeCycleDuration.Observe(time.Since(cycleStart).Seconds())
+			return
+		case <-beat:
+			s.Heartbeat.Beat()
+		}
+	}
+}
+
+// ScrapeOnce claims up to Concurrency webpages that are not leased by
+// another worker and fetches them in parallel.
+func (s *Scraper) ScrapeOnce(ctx context.Context) ([]FeedResult, error) {
+	pages, err := s.DB.ClaimWebpagesToFetch(ctx, database.ClaimWebpagesToFetchParams{
+		LeaseOwner:   s.WorkerID,
+		LeaseSeconds: s.LeaseDuration.Seconds(),
+		MaxWebpages:  int32(s.Concurrency),
+	})
 	if err != nil {
-		log.Printf("Error marking feed as fetched: %v", err)
-		return
+		return nil, err
+	}
+
+	results := make([]FeedResult, len(pages))
+	wg := &sync.WaitGroup{}
+
+	for i, page := range pages {
+		wg.Add(1)
+
+		go func(i int, page database.Webpage) {
+			defer wg.Done()
+			defer s.releaseLease(page)
+			results[i] = s.ScrapeWebpage(ctx, page)
+		}(i, page)
 	}
+	wg.Wait()
 
-	rss, err := urlToRSS(page.Url)
+	s.ClusterPosts(ctx)
+	return results, nil
+}
+
+// WebpageBusyError reports a webpage that could not be claimed because
+// another worker holds its lease or it is backing off.
+type WebpageBusyError struct {
+	Webpage database.Webpage
+	// LeaseOwner is the worker holding the webpage until Until, or "" if
+	// the webpage is backing off until Until instead.
+	LeaseOwner string
+	Until      time.Time
+}
+
+func (e *WebpageBusyError) Error() string {
+	until := e.Until.UTC().Format(time.RFC3339)
+	if e.LeaseOwner != "" {
+		return fmt.Sprintf("%s is being scraped by %s until %s", e.Webpage.Name, e.LeaseOwner, until)
+	}
+	return fmt.Sprintf("%s is backing off until %s", e.Webpage.Name, until)
+}
+
+// webpageBusy explains why page, as read after a failed claim, could not be
+// claimed at now. It returns nil if page has become free since.
+func (s *Scraper) webpageBusy(page database.Webpage, now time.Time) error {
+	if page.LeaseExpiresAt.Valid && page.LeaseExpiresAt.Time.After(now) && page.LeaseOwner.String != s.WorkerID {
+		return &WebpageBusyError{Webpage: page, LeaseOwner: page.LeaseOwner.String, Until: page.LeaseExpiresAt.Time}
+	}
+	if page.BackoffUntil.Valid && page.BackoffUntil.Time.After(now) {
+		return &WebpageBusyError{Webpage: page, Until: page.BackoffUntil.Time}
+	}
+	return nil
+}
+
+// ScrapeWebpageByID claims the webpage with id like ScrapeOnce claims its
+// batch, scrapes it and releases it. It returns a *WebpageBusyError when
+// another worker holds the webpage or it is backing off, unless force is
+// set.
+func (s *Scraper) ScrapeWebpageByID(ctx context.Context, id uuid.UUID, force bool) (FeedResult, error) {
+	page, err := s.DB.ClaimWebpage(ctx, database.ClaimWebpageParams{
+		LeaseOwner:   s.WorkerID,
+		LeaseSeconds: s.LeaseDuration.Seconds(),
+		ID:           id,
+		Force:        force,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		page, err := s.DB.GetWebpageByID(ctx, id)
+		if err != nil {
+			return FeedResult{}, err
+		}
+		if err := s.webpageBusy(page, time.Now().UTC()); err != nil {
+			return FeedResult{}, err
+		}
+		return FeedResult{}, fmt.Errorf("%s was released while being claimed, try again", page.Name)
+	}
 	if err != nil {
-		log.Printf("Error scrapping feed: %v", err)
-		log.Printf("This is the problematic: %v", page.Url)
+		return FeedResult{}, err
+	}
+	defer s.releaseLease(page)
+
+	return s.ScrapeWebpage(ctx, page), nil
+}
+
+// ClusterPosts assigns the posts created since the last run to clusters.
+// Failures are only logged; the posts are picked up by the next run.
+func (s *Scraper) ClusterPosts(ctx context.Context) {
+	if s.Clusterer == nil {
 		return
 	}
+	if _, err := s.Clusterer.RunOnce(ctx); err != nil {
+		slog.Error("Error clustering posts", "error", err)
+	}
+}
 
-	log.Printf("Scrapped feed %v", page.Url)
-	log.Printf("Found %v channels", len(rss.Channel.Items))
+// releaseLease gives a webpage back as soon as it has been processed. It
+// uses a fresh context so leases are still released during shutdown.
+func (s *Scraper) releaseLease(page database.Webpage) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-	// log.Printf("All Rss Items: %v", rss)
+	err := s.DB.ReleaseWebpageLease(ctx, database.ReleaseWebpageLeaseParams{
+		ID:         page.ID,
+		LeaseOwner: sql.NullString{String: s.WorkerID, Valid: true},
+	})
+	if err != nil {
+		slog.Warn("Error releasing webpage lease", "webpage_id", page.ID, "error", err)
+	}
+}
 
-	for _, item := range rss.Channel.Items {
+// ScrapeWebpage fetches a single feed and stores its items. Everything the
+// scrape writes is committed in one transaction, so a failure part way
+// through leaves neither a partial set of posts nor a misleading fetch mark.
+func (s *Scraper) ScrapeWebpage(ctx context.Context, page database.Webpage) (result FeedResult) {
+	result.Webpage = page
 
-		description := sql.NullString{}
-		if item.Description != "" {
-			description = sql.NullString{String: item.Description, Valid: true}
-		}
+	logger := slog.With("webpage_id", page.ID, "url", page.Url)
+	start := time.Now()
+	outcome := metrics.OutcomeSuccess
+	defer func() {
+		result.Duration = time.Since(start)
+		metrics.FeedFetches.WithLabelValues(page.ID.String(), outcome).Inc()
+		metrics.FeedFetchDuration.WithLabelValues(outcome).Observe(result.Duration.Seconds())
+		metrics.FeedLastFetchDuration.WithLabelValues(page.ID.String()).Set(result.Duration.Seconds())
+	}()
 
-		pageName := sql.NullString{}
-		if page.Name != "" {
-			pageName = sql.NullString{String: page.Name, Valid: true}
+	rss, movedTo, err := urlToRSS(ctx, s.Fetcher, page.Url, page.UserAgent.String)
+	if err != nil {
+		logger.Error("Error scraping feed", "error", err, "duration", time.Since(start))
+		outcome = metrics.OutcomeFetchError
+		result.Err = err
+		status := FetchStatusFetchError
+		var blocked *fetcher.RobotsDisallowedError
+		if errors.As(err, &blocked) {
+			status = FetchStatusBlocked
 		}
+		s.recordFailure(ctx, page, status, err)
+		return result
+	}
+	result.Found = len(rss.Channel.Items)
+	metrics.FeedItems.WithLabelValues(metrics.ItemParsed).Add(float64(result.Found))
 
-		publishedAt := sql.NullTime{}
-		if item.PubDate != "" {
-			t, err := parseDate(item.PubDate)
-			if err != nil {
-				log.Printf("Error parsing pubDate: %v", err)
-				log.Printf("Undefined pubDate for item %v", item.PubDate)
-				continue
-			}
+	items := s.prepareItems(logger, page, rss.Channel.Items, &result)
 
-			// Successfully parsed pubDate
-			publishedAt = sql.NullTime{Time: t, Valid: true}
+	siteURL := channelSiteURL(rss.Channel, page.Url)
+	if err := s.ingest(ctx, page, items, movedTo, siteURL, &result); err != nil {
+		logger.Error("Error storing feed items", "error", err)
+		outcome = metrics.OutcomeStoreError
+		result.Err = err
+		s.recordFailure(ctx, page, FetchStatusStoreError, err)
+		return result
+	}
 
-			twoMonthsAgo := time.Now().UTC().AddDate(0, -2, 0)
-			if t.Before(twoMonthsAgo) {
-				log.Printf("Skipping item %v because it's older than two months", item.Title)
-				continue
-			}
+	metrics.FeedItems.WithLabelValues(metrics.ItemCreated).Add(float64(result.Created))
+	metrics.FeedItems.WithLabelValues(metrics.ItemUpdated).Add(float64(result.Updated))
+	metrics.FeedItems.WithLabelValues(metrics.ItemDuplicate).Add(float64(result.Duplicates))
+	logger.Info("Scraped feed",
+		"duration", time.Since(start),
+		"items_found", result.Found,
+		"items_created", result.Created,
+		"items_updated", result.Updated,
+		"items_duplicate", result.Duplicates,
+		"items_skipped", result.Skipped,
+	)
+
+	return result
+}
+
+// prepareItems turns feed items into rows ready for insertion, dropping
+// items that cannot or should not be stored.
+func (s *Scraper) prepareItems(logger *slog.Logger, page database.Webpage, rssItems []RSSItem, result *FeedResult) []ingestItem {
+	items := make([]ingestItem, 0, len(rssItems))
+	seen := make(map[string]bool, len(rssItems))
+	cutoff := s.itemCutoff(page)
+
+	fetchedAt := time.Now().UTC()
+
+	for _, item := range rssItems {
+		publishedAt, ok := itemDate(item)
+		if !ok {
+			logger.Debug("Using fetch time for item with unparseable date",
+				"title", item.Title,
+				"pub_date", item.PubDate,
+				"dc_date", item.DCDate,
+				"updated", item.Updated,
+			)
+			metrics.FeedItems.WithLabelValues(metrics.ItemDateFallback).Inc()
+			publishedAt = fetchedAt
+		}
+
+		if !cutoff.IsZero() && !publishedAt.IsZero() && publishedAt.Before(cutoff) {
+			logger.Debug("Skipping item older than ingest cutoff", "title", item.Title, "cutoff", cutoff)
+			metrics.FeedItems.WithLabelValues(metrics.ItemSkippedTooOld).Inc()
+			result.Skipped++
+			continue
 		}
 
 		if item.Link == "" {
-			log.Printf("Undefined link for item %v", item.Title)
+			logger.Debug("Skipping item without link", "title", item.Title)
+			metrics.FeedItems.WithLabelValues(metrics.ItemSkippedInvalid).Inc()
+			result.Skipped++
 			continue
 		}
-		post, err := db.CreatePost(context.Background(), database.CreatePostParams{
-			ID:          uuid.New(),
-			CreatedAt:   time.Now().UTC(),
-			UpdatedAt:   time.Now().UTC(),
-			Title:       item.Title,
-			Description: description,
-			Url:         item.Link,
-			PublishedAt: publishedAt,
-			Postname:    pageName,
-		})
-		if err != nil {
-			if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
-				// log.Printf("Post %v already exists", post.Url)
-				continue
-			}
-			log.Printf("Error creating post: %v", err)
-			return
+
+		// A feed listing the same link twice would make the upsert touch
+		// one row twice, which Postgres rejects.
+		if seen[item.Link] {
+			result.Duplicates++
+			continue
 		}
+		seen[item.Link] = true
 
-		log.Printf("Created post %v", post.Url)
+		// Descriptions are publisher markup; only a safe subset is kept,
+		// with links made absolute against the item.
+		description := content.Sanitize(item.Description, item.Link)
+		items = append(items, ingestItem{
+			Title:           item.Title,
+			Description:     description,
+			DescriptionText: content.PlainText(description),
+			Link:            item.Link,
+			PublishedAt:     publishedAt,
+			Author:          itemAuthor(item),
+			Categories:      itemCategories(item),
+			Enclosures:      itemEnclosures(item),
+		})
 	}
 
+	return items
 }
 
-func parseDate(pubDate string) (time.Time, error) {
-	// Define the possible date formats
-	formats := []string{
-		time.RFC1123,  // Example: "Mon, 02 Jan 2006 15:04:05 MST"
-		time.RFC1123Z, // Example: "Mon, 02 Jan 2006 15:04:05 -0700"
-		time.RFC3339,  // Example: "2006-01-02T15:04:05Z07:00"
-		time.RFC822,   // Example: "02 Jan 06 15:04 MST"
-		time.RFC822Z,  // Example: "02 Jan 06 15:04 -0700"
-		time.RFC850,   // Example: "Monday, 02-Jan-06 15:04:05 MST"
-		time.RubyDate, // Example: "Mon Jan 02 15:04:05 -0700 2006"
+// channelSiteURL returns the website a feed fetched from feedURL says it
+// belongs to, or "" if the channel link is missing or not an http or https
+// URL. Relative links are resolved against feedURL.
+func channelSiteURL(channel GenericChannel, feedURL string) string {
+	link := strings.TrimSpace(channel.Link)
+	if link == "" {
+		return ""
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
 	}
+	if base, err := url.Parse(feedURL); err == nil {
+		u = base.ResolveReference(u)
+	}
+	if !ValidFeedURL(u.String()) {
+		return ""
+	}
+	return u.String()
+}
 
-	// Iterate through the formats and try parsing
-	for _, format := range formats {
-		t, err := time.Parse(format, pubDate)
-		if err == nil {
-			return t, nil // Successfully parsed
-		}
+// itemCutoff returns the oldest publication date accepted for page, or the
+// zero time when items of any age are accepted.
+func (s *Scraper) itemCutoff(page database.Webpage) time.Time {
+	maxAge := s.MaxItemAge
+	if page.MaxItemAgeDays.Valid {
+		maxAge = time.Duration(page.MaxItemAgeDays.Int32) * 24 * time.Hour
+	}
+	if maxAge <= 0 {
+		return time.Time{}
 	}
+	return time.Now().UTC().Add(-maxAge)
+}
 
-	// If all formats fail, return an error
-	return time.Time{}, fmt.Errorf("unknown date format: %s", pubDate)
+// itemDate returns the first parseable date among an item's pubDate,
+// dc:date and Atom updated elements. An item without any date yields the
+// zero time and true, as it did before; ok is false only when a date was
+// present but none could be read.
+func itemDate(item RSSItem) (t time.Time, ok bool) {
+	present := false
+	for _, value := range []string{item.PubDate, item.DCDate, item.Updated} {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		present = true
+		if t, err := parseDate(value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, !present
 }
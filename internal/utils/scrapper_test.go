@@ -0,0 +1,235 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cyberkillua/dailyread/internal/database"
+	"github.com/cyberkillua/dailyread/internal/fetcher"
+)
+
+func TestURLToRSS(t *testing.T) {
+	latin1, err := os.ReadFile(filepath.Join("testdata", "latin1-header.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write(latin1)
+	zw.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != feedAccept {
+			t.Errorf("Accept = %q", r.Header.Get("Accept"))
+		}
+		w.Header().Set("Content-Type", "application/rss+xml; charset=ISO-8859-1")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(compressed.Bytes())
+	})
+	mux.Handle("/old", http.RedirectHandler("/feed", http.StatusMovedPermanently))
+	mux.Handle("/promo", http.RedirectHandler("/feed", http.StatusFound))
+	mux.HandleFunc("/missing", http.NotFound)
+	mux.HandleFunc("/huge", func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		w.Write(bytes.Repeat([]byte("<rss>"), 1024))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := fetcher.New(fetcher.Options{HostInterval: -1, IgnoreRobots: true, MaxBodySize: 4096})
+	ctx := context.Background()
+
+	t.Run("compressed latin-1 feed", func(t *testing.T) {
+		feed, movedTo, err := urlToRSS(ctx, f, srv.URL+"/feed", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if movedTo != "" {
+			t.Errorf("movedTo = %q, want none", movedTo)
+		}
+		if len(feed.Channel.Items) != 1 || feed.Channel.Items[0].Title != "Café crème" {
+			t.Errorf("items = %+v", feed.Channel.Items)
+		}
+	})
+
+	t.Run("permanent redirect", func(t *testing.T) {
+		_, movedTo, err := urlToRSS(ctx, f, srv.URL+"/old", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if movedTo != srv.URL+"/feed" {
+			t.Errorf("movedTo = %q, want %q", movedTo, srv.URL+"/feed")
+		}
+	})
+
+	t.Run("temporary redirect", func(t *testing.T) {
+		_, movedTo, err := urlToRSS(ctx, f, srv.URL+"/promo", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if movedTo != "" {
+			t.Errorf("movedTo = %q, want none", movedTo)
+		}
+	})
+
+	t.Run("status error", func(t *testing.T) {
+		_, _, err := urlToRSS(ctx, f, srv.URL+"/missing", "")
+		var status *fetcher.HTTPStatusError
+		if !errors.As(err, &status) || status.StatusCode != http.StatusNotFound {
+			t.Errorf("urlToRSS = %v, want a 404 *fetcher.HTTPStatusError", err)
+		}
+	})
+
+	t.Run("body too large", func(t *testing.T) {
+		_, _, err := urlToRSS(ctx, f, srv.URL+"/huge", "")
+		var tooLarge *fetcher.BodyTooLargeError
+		if !errors.As(err, &tooLarge) {
+			t.Errorf("urlToRSS = %v, want *fetcher.BodyTooLargeError", err)
+		}
+	})
+}
+
+func TestPrepareItems(t *testing.T) {
+	recent := time.Now().UTC().Add(-time.Hour).Format(time.RFC1123Z)
+	old := time.Now().UTC().Add(-90 * 24 * time.Hour).Format(time.RFC1123Z)
+
+	s := &Scraper{MaxItemAge: 60 * 24 * time.Hour}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	var result FeedResult
+	items := s.prepareItems(logger, database.Webpage{}, []RSSItem{
+		{
+			Title:       "Kept",
+			Link:        "https://example.com/posts/1",
+			PubDate:     recent,
+			Description: `<p onclick="steal()">Read <a href="javascript:alert(1)">this</a> and <a href="../about">that</a>.</p><script>alert(1)</script><img src="/pixel.gif" width="1" height="1">`,
+		},
+		{Title: "Duplicate", Link: "https://example.com/posts/1", PubDate: recent},
+		{Title: "No link", PubDate: recent},
+		{Title: "Too old", Link: "https://example.com/posts/2", PubDate: old},
+		{Title: "Undated", Link: "https://example.com/posts/3"},
+	}, &result)
+
+	if len(items) != 2 || items[0].Title != "Kept" || items[1].Title != "Undated" {
+		t.Fatalf("items = %+v", items)
+	}
+	if result.Duplicates != 1 || result.Skipped != 2 {
+		t.Errorf("duplicates = %d, skipped = %d, want 1 and 2", result.Duplicates, result.Skipped)
+	}
+
+	wantHTML := `<p>Read <a>this</a> and <a href="https://example.com/about" rel="nofollow noopener noreferrer">that</a>.</p>`
+	if items[0].Description != wantHTML {
+		t.Errorf("description = %q, want %q", items[0].Description, wantHTML)
+	}
+	if items[0].DescriptionText != "Read this and that." {
+		t.Errorf("description text = %q", items[0].DescriptionText)
+	}
+}
+
+func TestItemCutoff(t *testing.T) {
+	s := &Scraper{MaxItemAge: 30 * 24 * time.Hour}
+
+	cutoff := s.itemCutoff(database.Webpage{})
+	if age := time.Since(cutoff); age < 29*24*time.Hour || age > 31*24*time.Hour {
+		t.Errorf("default cutoff is %s ago", age)
+	}
+
+	page := database.Webpage{MaxItemAgeDays: sql.NullInt32{Int32: 7, Valid: true}}
+	if age := time.Since(s.itemCutoff(page)); age < 6*24*time.Hour || age > 8*24*time.Hour {
+		t.Errorf("webpage cutoff is %s ago", age)
+	}
+
+	page.MaxItemAgeDays.Int32 = 0
+	if !s.itemCutoff(page).IsZero() {
+		t.Error("a webpage allowing any age still has a cutoff")
+	}
+}
+
+func TestChannelSiteURL(t *testing.T) {
+	const feedURL = "https://blog.example.com/feeds/all.xml"
+	tests := []struct {
+		link string
+		want string
+	}{
+		{"https://example.com/", "https://example.com/"},
+		{"  https://example.com/blog  ", "https://example.com/blog"},
+		{"/", "https://blog.example.com/"},
+		{"../", "https://blog.example.com/"},
+		{"//example.com/", "https://example.com/"},
+		{"", ""},
+		{"javascript:alert(1)", ""},
+		{"mailto:editor@example.com", ""},
+		{"http://[::1", ""},
+	}
+	for _, tt := range tests {
+		if got := channelSiteURL(GenericChannel{Link: tt.link}, feedURL); got != tt.want {
+			t.Errorf("channelSiteURL(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestWebpageBusy(t *testing.T) {
+	s := &Scraper{WorkerID: "cli-1"}
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	later := sql.NullTime{Time: now.Add(5 * time.Minute), Valid: true}
+	earlier := sql.NullTime{Time: now.Add(-5 * time.Minute), Valid: true}
+
+	tests := []struct {
+		name string
+		page database.Webpage
+		want string
+	}{
+		{
+			"leased by another worker",
+			database.Webpage{Name: "Example", LeaseOwner: sql.NullString{String: "worker-7", Valid: true}, LeaseExpiresAt: later},
+			"Example is being scraped by worker-7 until 2024-01-02T12:05:00Z",
+		},
+		{
+			"backing off",
+			database.Webpage{Name: "Example", BackoffUntil: later},
+			"Example is backing off until 2024-01-02T12:05:00Z",
+		},
+		{
+			"lease reported before backoff",
+			database.Webpage{Name: "Example", LeaseOwner: sql.NullString{String: "worker-7", Valid: true}, LeaseExpiresAt: later, BackoffUntil: later},
+			"Example is being scraped by worker-7 until 2024-01-02T12:05:00Z",
+		},
+		{
+			"expired lease and backoff",
+			database.Webpage{Name: "Example", LeaseOwner: sql.NullString{String: "worker-7", Valid: true}, LeaseExpiresAt: earlier, BackoffUntil: earlier},
+			"",
+		},
+		{
+			"own lease",
+			database.Webpage{Name: "Example", LeaseOwner: sql.NullString{String: "cli-1", Valid: true}, LeaseExpiresAt: later},
+			"",
+		},
+		{"free", database.Webpage{Name: "Example"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.webpageBusy(tt.page, now)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("webpageBusy = %v, want nil", err)
+				}
+				return
+			}
+			var busy *WebpageBusyError
+			if !errors.As(err, &busy) || err.Error() != tt.want {
+				t.Errorf("webpageBusy = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+-- name: ListUnclusteredPosts :many
+SELECT id, webpage_id, title, url, COALESCE(published_at, created_at)::timestamp AS sort_at
+FROM posts
+WHERE cluster_id IS NULL
+ORDER BY created_at ASC, id ASC
+LIMIT $1;
+
+
+-- name: GetClusterCandidates :many
+SELECT id, webpage_id, cluster_id, simhash, url_key, COALESCE(published_at, created_at)::timestamp AS sort_at
+FROM posts
+WHERE cluster_id IS NOT NULL
+AND (
+    COALESCE(published_at, created_at) BETWEEN sqlc.arg(from_at)::timestamp AND sqlc.arg(to_at)::timestamp
+    OR url_key = ANY(sqlc.arg(url_keys)::text[])
+);
+
+
+-- name: SetPostClusters :exec
+UPDATE posts
+SET cluster_id = clustered.cluster_id,
+simhash = clustered.simhash,
+url_key = clustered.url_key
+FROM (
+    SELECT
+        unnest(sqlc.arg(ids)::uuid[]) AS id,
+        unnest(sqlc.arg(cluster_ids)::uuid[]) AS cluster_id,
+        unnest(sqlc.arg(simhashes)::bigint[]) AS simhash,
+        unnest(sqlc.arg(url_keys)::text[]) AS url_key
+) AS clustered
+WHERE posts.id = clustered.id;
+
+
+-- name: GetPostsInClusters :many
+SELECT id, created_at, updated_at, title, description, url, published_at, postname, webpage_id, starred, description_text, author, categories, enclosures, read, score, cluster_id, simhash, url_key, tagged_at
+FROM posts
+WHERE cluster_id = ANY(sqlc.arg(cluster_ids)::uuid[])
+ORDER BY COALESCE(published_at, created_at) ASC, id ASC;
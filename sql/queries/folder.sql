@@ -0,0 +1,95 @@
+-- name: CreateFolder :one
+INSERT INTO folders (id, created_at, updated_at, name, parent_id)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+
+-- name: GetFolderByID :one
+SELECT * FROM folders
+WHERE id = $1;
+
+
+-- name: GetFolderByName :one
+SELECT * FROM folders
+WHERE parent_id IS NOT DISTINCT FROM sqlc.narg(parent_id)::uuid
+AND lower(name) = lower(sqlc.arg(name)::text);
+
+
+-- name: IsFolderAncestor :one
+-- Reports whether ancestor_id is folder_id itself or one of the folders it
+-- is nested in.
+WITH RECURSIVE ancestors AS (
+    SELECT folders.id, folders.parent_id FROM folders WHERE folders.id = sqlc.arg(folder_id)::uuid
+    UNION
+    SELECT folders.id, folders.parent_id FROM folders JOIN ancestors ON folders.id = ancestors.parent_id
+)
+SELECT EXISTS (SELECT 1 FROM ancestors WHERE ancestors.id = sqlc.arg(ancestor_id)::uuid);
+
+
+-- name: ListFolders :many
+SELECT * FROM folders
+ORDER BY lower(name) ASC;
+
+
+-- name: UpdateFolder :one
+UPDATE folders
+SET name = $2,
+parent_id = $3,
+updated_at = $4
+WHERE id = $1
+RETURNING *;
+
+
+-- name: DeleteFolder :execrows
+DELETE FROM folders
+WHERE id = $1;
+
+
+-- name: AddWebpageToFolder :exec
+INSERT INTO webpage_folders (webpage_id, folder_id, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (webpage_id, folder_id) DO NOTHING;
+
+
+-- name: RemoveWebpageFromFolder :execrows
+DELETE FROM webpage_folders
+WHERE webpage_id = $1 AND folder_id = $2;
+
+
+-- name: LockFolders :exec
+-- Serializes changes to the folder tree, so two moves cannot each pass the
+-- cycle check and together form a cycle.
+LOCK TABLE folders IN SHARE ROW EXCLUSIVE MODE;
+
+
+-- name: ListWebpageFolders :many
+SELECT * FROM webpage_folders
+ORDER BY created_at ASC;
+
+
+-- name: ListWebpagesInFolder :many
+WITH RECURSIVE subtree AS (
+    SELECT folders.id FROM folders WHERE folders.id = sqlc.arg(folder_id)::uuid
+    UNION
+    SELECT folders.id FROM folders JOIN subtree ON folders.parent_id = subtree.id
+)
+SELECT * FROM webpages
+WHERE webpages.id IN (
+    SELECT webpage_id FROM webpage_folders
+    WHERE folder_id IN (SELECT id FROM subtree)
+)
+ORDER BY name ASC;
+
+
+-- name: GetFolderUnreadCounts :many
+WITH RECURSIVE tree AS (
+    SELECT folders.id AS root_id, folders.id AS folder_id FROM folders
+    UNION
+    SELECT tree.root_id, folders.id FROM folders JOIN tree ON folders.parent_id = tree.folder_id
+)
+SELECT tree.root_id::uuid AS folder_id, COUNT(DISTINCT posts.id) AS unread_count
+FROM tree
+JOIN webpage_folders ON webpage_folders.folder_id = tree.folder_id
+JOIN posts ON posts.webpage_id = webpage_folders.webpage_id
+WHERE NOT posts.read
+GROUP BY tree.root_id;
@@ -1,11 +1,254 @@
 -- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, description, url, published_at, postName)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+INSERT INTO posts (id, created_at, updated_at, title, description, url, published_at, postName, webpage_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 RETURNING *;
 
 
 -- name: GetPosts :many
-SELECT id, created_at, updated_at, title, description, url, published_at, postname 
+SELECT id, created_at, updated_at, title, description, url, published_at, postname, webpage_id, starred, description_text, author, categories, enclosures, read, score, cluster_id, simhash, url_key, tagged_at
 FROM posts 
 ORDER BY created_at DESC 
 LIMIT 30;
+
+
+-- name: GetPostsInFolder :many
+WITH RECURSIVE subtree AS (
+    SELECT folders.id FROM folders WHERE folders.id = sqlc.arg(folder_id)::uuid
+    UNION
+    SELECT folders.id FROM folders JOIN subtree ON folders.parent_id = subtree.id
+)
+SELECT id, created_at, updated_at, title, description, url, published_at, postname, webpage_id, starred, description_text, author, categories, enclosures, read, score, cluster_id, simhash, url_key, tagged_at
+FROM posts
+WHERE webpage_id IN (
+    SELECT webpage_id FROM webpage_folders
+    WHERE folder_id IN (SELECT id FROM subtree)
+)
+ORDER BY created_at DESC
+LIMIT 30;
+
+
+-- name: GetTrendingPosts :many
+WITH RECURSIVE subtree AS (
+    SELECT folders.id FROM folders WHERE folders.id = sqlc.narg(folder_id)::uuid
+    UNION
+    SELECT folders.id FROM folders JOIN subtree ON folders.parent_id = subtree.id
+)
+SELECT id, created_at, updated_at, title, description, url, published_at, postname, webpage_id, starred, description_text, author, categories, enclosures, read, score, cluster_id, simhash, url_key, tagged_at
+FROM posts
+WHERE score > 0
+AND (sqlc.narg(folder_id)::uuid IS NULL OR webpage_id IN (
+    SELECT webpage_id FROM webpage_folders
+    WHERE folder_id IN (SELECT id FROM subtree)
+))
+AND (sqlc.narg(tag)::text IS NULL OR id IN (SELECT post_id FROM post_tags WHERE tag = sqlc.narg(tag)::text))
+ORDER BY score DESC, id ASC
+LIMIT 30;
+
+
+-- name: GetPostByID :one
+SELECT * FROM posts
+WHERE id = $1;
+
+
+-- name: GetPreviousPostID :one
+SELECT id FROM posts
+WHERE webpage_id = sqlc.arg(webpage_id)
+AND (COALESCE(published_at, created_at), id) < (sqlc.arg(sort_at)::timestamp, sqlc.arg(id)::uuid)
+ORDER BY COALESCE(published_at, created_at) DESC, id DESC
+LIMIT 1;
+
+
+-- name: GetNextPostID :one
+SELECT id FROM posts
+WHERE webpage_id = sqlc.arg(webpage_id)
+AND (COALESCE(published_at, created_at), id) > (sqlc.arg(sort_at)::timestamp, sqlc.arg(id)::uuid)
+ORDER BY COALESCE(published_at, created_at) ASC, id ASC
+LIMIT 1;
+
+
+-- name: GetFeedPosts :many
+SELECT * FROM posts
+ORDER BY COALESCE(published_at, created_at) DESC
+LIMIT $1;
+
+
+-- name: GetFeedPostsByWebpage :many
+SELECT * FROM posts
+WHERE webpage_id = $1
+ORDER BY COALESCE(published_at, created_at) DESC
+LIMIT $2;
+
+
+-- name: UpsertPosts :many
+INSERT INTO posts (id, created_at, updated_at, title, description, description_text, url, published_at, postName, webpage_id, author, categories, enclosures)
+SELECT
+    unnest(sqlc.arg(ids)::uuid[]),
+    sqlc.arg(now)::timestamp,
+    sqlc.arg(now)::timestamp,
+    unnest(sqlc.arg(titles)::text[]),
+    NULLIF(unnest(sqlc.arg(descriptions)::text[]), ''),
+    unnest(sqlc.arg(description_texts)::text[]),
+    unnest(sqlc.arg(urls)::text[]),
+    NULLIF(unnest(sqlc.arg(published_ats)::text[]), '')::timestamp,
+    sqlc.narg(postname)::text,
+    sqlc.arg(webpage_id)::uuid,
+    NULLIF(unnest(sqlc.arg(authors)::text[]), ''),
+    unnest(sqlc.arg(categories)::text[])::jsonb,
+    unnest(sqlc.arg(enclosures)::text[])::jsonb
+ON CONFLICT (url) DO UPDATE
+SET title = EXCLUDED.title,
+description = EXCLUDED.description,
+description_text = EXCLUDED.description_text,
+author = EXCLUDED.author,
+categories = EXCLUDED.categories,
+enclosures = EXCLUDED.enclosures,
+published_at = COALESCE(EXCLUDED.published_at, posts.published_at),
+updated_at = EXCLUDED.updated_at,
+-- A post whose text or categories changed is tagged again.
+tagged_at = CASE
+    WHEN posts.title IS DISTINCT FROM EXCLUDED.title
+    OR posts.description_text IS DISTINCT FROM EXCLUDED.description_text
+    OR posts.categories IS DISTINCT FROM EXCLUDED.categories
+    THEN NULL
+    ELSE posts.tagged_at
+END
+WHERE posts.title IS DISTINCT FROM EXCLUDED.title
+OR posts.description IS DISTINCT FROM EXCLUDED.description
+OR posts.author IS DISTINCT FROM EXCLUDED.author
+OR posts.categories IS DISTINCT FROM EXCLUDED.categories
+OR posts.enclosures IS DISTINCT FROM EXCLUDED.enclosures
+RETURNING id, (xmax = 0)::boolean AS inserted;
+
+
+-- name: SetPostStarred :one
+-- Reader state leaves updated_at alone; it dates the post's content and
+-- drives feed caching.
+UPDATE posts
+SET starred = $2
+WHERE id = $1
+RETURNING *;
+
+
+-- name: SetPostRead :one
+-- Reader state leaves updated_at alone; it dates the post's content and
+-- drives feed caching.
+UPDATE posts
+SET read = $2
+WHERE id = $1
+RETURNING *;
+
+
+-- name: ScoreRecentPosts :execrows
+-- Posts score higher the more sources carry the same link or headline and
+-- the more the reader has interacted with them, and decay with age.
+WITH recent AS (
+    SELECT
+        id,
+        webpage_id,
+        GREATEST(EXTRACT(EPOCH FROM (sqlc.arg(now)::timestamp - COALESCE(published_at, created_at))) / 3600, 0)::float8 AS age_hours,
+        regexp_replace(lower(url), '^https?://(www\.)?|[?#].*$|/+$', '', 'g') AS url_key,
+        btrim(regexp_replace(lower(title), '[^[:alnum:]]+', ' ', 'g')) AS title_key
+    FROM posts
+    WHERE COALESCE(published_at, created_at) >= sqlc.arg(window_start)::timestamp
+),
+url_sources AS (
+    SELECT url_key, COUNT(DISTINCT webpage_id) AS sources
+    FROM recent
+    GROUP BY url_key
+),
+title_sources AS (
+    SELECT title_key, COUNT(DISTINCT webpage_id) AS sources
+    FROM recent
+    WHERE length(title_key) >= 20
+    GROUP BY title_key
+)
+UPDATE posts
+SET score = (
+    1
+    + sqlc.arg(coverage_weight)::float8 * ln(GREATEST(url_sources.sources, COALESCE(title_sources.sources, 0), 1))
+    + CASE WHEN posts.starred THEN sqlc.arg(star_weight)::float8 ELSE 0 END
+    + CASE WHEN posts.read THEN sqlc.arg(read_weight)::float8 ELSE 0 END
+) / power(recent.age_hours + 2, sqlc.arg(gravity)::float8)
+FROM recent
+JOIN url_sources ON url_sources.url_key = recent.url_key
+LEFT JOIN title_sources ON title_sources.title_key = recent.title_key
+WHERE posts.id = recent.id;
+
+
+-- name: ResetStalePostScores :execrows
+UPDATE posts
+SET score = 0
+WHERE score <> 0
+AND COALESCE(published_at, created_at) < sqlc.arg(window_start)::timestamp;
+
+
+-- name: DeleteExpiredPosts :execrows
+-- Removed posts leave the corpus keywords are weighed against, so their
+-- terms are taken off the document frequencies. Those rows are locked in
+-- term order, as the tagger locks them, so the two cannot deadlock.
+WITH expired AS (
+    SELECT id FROM posts
+    WHERE COALESCE(published_at, created_at) < sqlc.arg(cutoff)::timestamp
+    AND NOT starred
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE
+),
+dropped AS (
+    SELECT term, COUNT(*)::integer AS documents
+    FROM post_terms, jsonb_array_elements_text(post_terms.terms) AS term
+    WHERE post_terms.post_id IN (SELECT id FROM expired)
+    GROUP BY term
+),
+locked AS (
+    SELECT term_documents.term FROM term_documents
+    WHERE term_documents.term IN (SELECT term FROM dropped)
+    ORDER BY term_documents.term
+    FOR UPDATE
+),
+uncounted AS (
+    UPDATE term_documents
+    SET documents = term_documents.documents - dropped.documents
+    FROM dropped
+    WHERE term_documents.term = dropped.term
+    AND term_documents.term IN (SELECT term FROM locked)
+)
+DELETE FROM posts
+WHERE id IN (SELECT id FROM expired);
+
+
+-- name: ArchiveExpiredPosts :execrows
+-- Archived posts leave the corpus like deleted ones; see
+-- DeleteExpiredPosts.
+WITH expired AS (
+    DELETE FROM posts
+    WHERE id IN (
+        SELECT id FROM posts
+        WHERE COALESCE(published_at, created_at) < sqlc.arg(cutoff)::timestamp
+        AND NOT starred
+        LIMIT sqlc.arg(batch_size)
+    )
+    RETURNING id, created_at, updated_at, title, description, url, published_at, postName, webpage_id, description_text, author, categories, enclosures
+),
+dropped AS (
+    SELECT term, COUNT(*)::integer AS documents
+    FROM post_terms, jsonb_array_elements_text(post_terms.terms) AS term
+    WHERE post_terms.post_id IN (SELECT id FROM expired)
+    GROUP BY term
+),
+locked AS (
+    SELECT term_documents.term FROM term_documents
+    WHERE term_documents.term IN (SELECT term FROM dropped)
+    ORDER BY term_documents.term
+    FOR UPDATE
+),
+uncounted AS (
+    UPDATE term_documents
+    SET documents = term_documents.documents - dropped.documents
+    FROM dropped
+    WHERE term_documents.term = dropped.term
+    AND term_documents.term IN (SELECT term FROM locked)
+)
+INSERT INTO posts_archive (id, created_at, updated_at, title, description, url, published_at, postName, webpage_id, description_text, author, categories, enclosures, archived_at)
+SELECT id, created_at, updated_at, title, description, url, published_at, postName, webpage_id, description_text, author, categories, enclosures, sqlc.arg(archived_at)::timestamp
+FROM expired
+ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,55 @@
+-- name: QueuePostContents :exec
+INSERT INTO post_contents (post_id, created_at, updated_at)
+SELECT unnest(sqlc.arg(post_ids)::uuid[]), sqlc.arg(now)::timestamp, sqlc.arg(now)::timestamp
+ON CONFLICT (post_id) DO NOTHING;
+
+
+-- name: ClaimPostContents :many
+WITH claimed AS (
+    UPDATE post_contents
+    SET status = 'fetching',
+    attempts = attempts + 1,
+    retry_at = sqlc.arg(lease_expires_at)::timestamp,
+    updated_at = sqlc.arg(now)::timestamp
+    WHERE post_id IN (
+        SELECT post_id FROM post_contents
+        WHERE status IN ('pending', 'fetching')
+        AND (retry_at IS NULL OR retry_at < sqlc.arg(now)::timestamp)
+        ORDER BY created_at ASC
+        LIMIT sqlc.arg(batch_size)
+        FOR UPDATE SKIP LOCKED
+    )
+    RETURNING post_id, attempts
+)
+SELECT claimed.post_id, claimed.attempts, posts.url, webpages.user_agent
+FROM claimed
+JOIN posts ON posts.id = claimed.post_id
+LEFT JOIN webpages ON webpages.id = posts.webpage_id;
+
+
+-- name: StorePostContent :exec
+UPDATE post_contents
+SET status = 'done',
+content = sqlc.arg(content)::text,
+content_text = sqlc.arg(content_text)::text,
+word_count = sqlc.arg(word_count)::integer,
+last_error = NULL,
+retry_at = NULL,
+fetched_at = sqlc.arg(fetched_at)::timestamp,
+updated_at = sqlc.arg(fetched_at)::timestamp
+WHERE post_id = sqlc.arg(post_id);
+
+
+-- name: RecordPostContentFailure :exec
+UPDATE post_contents
+SET status = sqlc.arg(status)::text,
+last_error = sqlc.arg(error)::text,
+attempts = attempts - CASE WHEN sqlc.arg(refund_attempt)::boolean THEN 1 ELSE 0 END,
+retry_at = sqlc.narg(retry_at)::timestamp,
+updated_at = sqlc.arg(now)::timestamp
+WHERE post_id = sqlc.arg(post_id);
+
+
+-- name: GetPostContent :one
+SELECT * FROM post_contents
+WHERE post_id = $1;
@@ -0,0 +1,28 @@
+-- name: GetWebpageStats :many
+SELECT
+    webpages.id,
+    webpages.name,
+    webpages.url,
+    webpages.last_updated_at,
+    webpages.last_fetch_status,
+    webpages.last_fetch_error,
+    COUNT(posts.id) AS total_posts,
+    COUNT(posts.id) FILTER (WHERE NOT posts.read) AS unread_posts,
+    COUNT(posts.id) FILTER (WHERE COALESCE(posts.published_at, posts.created_at) >= sqlc.arg(day_ago)::timestamp) AS posts_last_day,
+    COUNT(posts.id) FILTER (WHERE COALESCE(posts.published_at, posts.created_at) >= sqlc.arg(week_ago)::timestamp) AS posts_last_week,
+    MAX(COALESCE(posts.published_at, posts.created_at)) AS last_post_at
+FROM webpages
+LEFT JOIN posts ON posts.webpage_id = webpages.id
+GROUP BY webpages.id
+ORDER BY webpages.name ASC;
+
+
+-- name: GetPostTotals :one
+SELECT
+    COUNT(*) AS total_posts,
+    COUNT(*) FILTER (WHERE NOT read) AS unread_posts,
+    COUNT(*) FILTER (WHERE starred) AS starred_posts,
+    COUNT(*) FILTER (WHERE COALESCE(published_at, created_at) >= sqlc.arg(day_ago)::timestamp) AS posts_last_day,
+    COUNT(*) FILTER (WHERE COALESCE(published_at, created_at) >= sqlc.arg(week_ago)::timestamp) AS posts_last_week,
+    MAX(COALESCE(published_at, created_at)) AS last_post_at
+FROM posts;
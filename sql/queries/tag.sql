@@ -0,0 +1,96 @@
+-- name: AddTermDocuments :exec
+-- documents holds the change to each term's count, which is negative for
+-- terms a re-tagged post no longer contains.
+INSERT INTO term_documents (term, documents)
+SELECT unnest(sqlc.arg(terms)::text[]), unnest(sqlc.arg(documents)::integer[])
+ON CONFLICT (term) DO UPDATE
+SET documents = term_documents.documents + EXCLUDED.documents;
+
+
+-- name: CountTaggedPosts :one
+SELECT COUNT(*) FROM posts
+WHERE tagged_at IS NOT NULL;
+
+
+-- name: DeletePostTags :exec
+DELETE FROM post_tags
+WHERE post_id = ANY(sqlc.arg(post_ids)::uuid[]);
+
+
+-- name: GetFeedPostsByTag :many
+SELECT posts.* FROM posts
+WHERE posts.id IN (SELECT post_id FROM post_tags WHERE tag = $1)
+ORDER BY COALESCE(posts.published_at, posts.created_at) DESC
+LIMIT $2;
+
+
+-- name: GetPostTags :many
+SELECT post_id, tag, source, score, created_at FROM post_tags
+WHERE post_id = $1
+ORDER BY score DESC, tag ASC;
+
+
+-- name: GetPostsByTag :many
+WITH RECURSIVE subtree AS (
+    SELECT folders.id FROM folders WHERE folders.id = sqlc.narg(folder_id)::uuid
+    UNION
+    SELECT folders.id FROM folders JOIN subtree ON folders.parent_id = subtree.id
+)
+SELECT id, created_at, updated_at, title, description, url, published_at, postname, webpage_id, starred, description_text, author, categories, enclosures, read, score, cluster_id, simhash, url_key, tagged_at
+FROM posts
+WHERE id IN (SELECT post_id FROM post_tags WHERE tag = sqlc.arg(tag)::text)
+AND (sqlc.narg(folder_id)::uuid IS NULL OR webpage_id IN (
+    SELECT webpage_id FROM webpage_folders
+    WHERE folder_id IN (SELECT id FROM subtree)
+))
+ORDER BY created_at DESC
+LIMIT 30;
+
+
+-- name: GetTermDocuments :many
+SELECT term, documents FROM term_documents
+WHERE term = ANY(sqlc.arg(terms)::text[]);
+
+
+-- name: InsertPostTags :exec
+INSERT INTO post_tags (post_id, tag, source, score, created_at)
+SELECT
+    unnest(sqlc.arg(post_ids)::uuid[]),
+    unnest(sqlc.arg(tags)::text[]),
+    unnest(sqlc.arg(sources)::text[]),
+    unnest(sqlc.arg(scores)::float8[]),
+    sqlc.arg(now)::timestamp
+ON CONFLICT (post_id, tag) DO NOTHING;
+
+
+-- name: ListTags :many
+SELECT tag, COUNT(*) AS posts
+FROM post_tags
+GROUP BY tag
+ORDER BY posts DESC, tag ASC
+LIMIT $1;
+
+
+-- name: ListUntaggedPosts :many
+-- terms are those counted when the post was last tagged, empty for a post
+-- that never was.
+SELECT posts.id, posts.title, posts.description_text, posts.categories, COALESCE(post_terms.terms, '[]')::jsonb AS terms
+FROM posts
+LEFT JOIN post_terms ON post_terms.post_id = posts.id
+WHERE posts.tagged_at IS NULL
+ORDER BY posts.created_at ASC, posts.id ASC
+LIMIT $1
+FOR UPDATE OF posts SKIP LOCKED;
+
+
+-- name: MarkPostsTagged :exec
+UPDATE posts
+SET tagged_at = sqlc.arg(now)::timestamp
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+
+-- name: SetPostTerms :exec
+INSERT INTO post_terms (post_id, terms)
+SELECT unnest(sqlc.arg(post_ids)::uuid[]), unnest(sqlc.arg(terms)::text[])::jsonb
+ON CONFLICT (post_id) DO UPDATE
+SET terms = EXCLUDED.terms;
@@ -1,6 +1,6 @@
 -- name: CreateWebpage :one
-INSERT INTO webpages (id, created_at, updated_at, name, url, type)
-VALUES ($1, $2, $3, $4, $5, $6)
+INSERT INTO webpages (id, created_at, updated_at, name, url, type, max_item_age_days, user_agent, full_text)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 RETURNING *;
 
 
@@ -15,4 +15,117 @@ UPDATE webpages
 SET last_updated_at = Now(), 
 updated_at = Now()
 WHERE id = $1
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: GetWebpageByID :one
+SELECT * FROM webpages
+WHERE id = $1;
+
+
+-- name: GetWebpageByURL :one
+SELECT * FROM webpages
+WHERE url = $1;
+
+
+-- name: GetWebpageByURLOrAlias :one
+SELECT * FROM webpages
+WHERE url = sqlc.arg(url)
+OR id = (SELECT webpage_id FROM webpage_url_aliases WHERE webpage_url_aliases.url = sqlc.arg(url));
+
+
+-- name: ListWebpages :many
+SELECT * FROM webpages
+ORDER BY name ASC;
+
+
+-- name: DeleteWebpage :execrows
+DELETE FROM webpages
+WHERE id = $1;
+
+
+-- name: ClaimWebpage :one
+-- Leases one webpage the way ClaimWebpagesToFetch does, returning no row if
+-- another worker holds it or it is backing off, unless force is set.
+UPDATE webpages
+SET lease_owner = sqlc.arg(lease_owner)::text,
+lease_expires_at = NOW() + make_interval(secs => sqlc.arg(lease_seconds)::float8)
+WHERE id = sqlc.arg(id)
+AND (
+    sqlc.arg(force)::boolean
+    OR (
+        (lease_expires_at IS NULL OR lease_expires_at < NOW() OR lease_owner = sqlc.arg(lease_owner)::text)
+        AND (backoff_until IS NULL OR backoff_until < NOW())
+    )
+)
+RETURNING *;
+
+
+-- name: ClaimWebpagesToFetch :many
+UPDATE webpages
+SET lease_owner = sqlc.arg(lease_owner)::text,
+lease_expires_at = NOW() + make_interval(secs => sqlc.arg(lease_seconds)::float8)
+WHERE id IN (
+    SELECT id FROM webpages
+    WHERE (lease_expires_at IS NULL OR lease_expires_at < NOW())
+    AND (backoff_until IS NULL OR backoff_until < NOW())
+    ORDER BY last_updated_at ASC NULLS FIRST
+    LIMIT sqlc.arg(max_webpages)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+
+-- name: ReleaseWebpageLease :exec
+UPDATE webpages
+SET lease_owner = NULL,
+lease_expires_at = NULL
+WHERE id = $1 AND lease_owner = $2;
+
+
+-- name: RecordWebpageFetch :exec
+UPDATE webpages
+SET last_updated_at = sqlc.arg(fetched_at)::timestamp,
+updated_at = sqlc.arg(fetched_at)::timestamp,
+last_fetch_status = sqlc.arg(status)::text,
+last_fetch_error = sqlc.narg(error)::text,
+backoff_until = sqlc.narg(backoff_until)::timestamp,
+site_url = COALESCE(sqlc.narg(site_url)::text, site_url)
+WHERE id = sqlc.arg(id);
+
+
+-- name: RecordWebpageRedirect :one
+UPDATE webpages
+SET redirect_count = CASE
+    WHEN sqlc.narg(redirect_url)::text IS NULL THEN 0
+    WHEN redirect_url = sqlc.narg(redirect_url)::text THEN redirect_count + 1
+    ELSE 1
+END,
+redirect_url = sqlc.narg(redirect_url)::text
+WHERE id = sqlc.arg(id)
+RETURNING redirect_count;
+
+
+-- name: MoveWebpageURL :exec
+UPDATE webpages
+SET url = sqlc.arg(url),
+redirect_url = NULL,
+redirect_count = 0,
+updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id);
+
+
+-- name: CreateWebpageURLAlias :exec
+INSERT INTO webpage_url_aliases (url, webpage_id, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (url) DO UPDATE SET webpage_id = EXCLUDED.webpage_id, created_at = EXCLUDED.created_at;
+
+
+-- name: DeleteWebpageURLAlias :exec
+DELETE FROM webpage_url_aliases
+WHERE url = $1;
+
+
+-- name: ListWebpageURLAliases :many
+SELECT * FROM webpage_url_aliases
+WHERE webpage_id = $1
+ORDER BY created_at DESC;
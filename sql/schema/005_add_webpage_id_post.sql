@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN webpage_id UUID REFERENCES webpages(id) ON DELETE CASCADE;
+
+UPDATE posts
+SET webpage_id = webpages.id
+FROM webpages
+WHERE posts.postName = webpages.name;
+
+CREATE INDEX posts_webpage_id_idx ON posts (webpage_id);
+
+-- +goose Down
+DROP INDEX posts_webpage_id_idx;
+ALTER TABLE posts DROP COLUMN webpage_id;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE webpages ADD COLUMN lease_owner TEXT;
+ALTER TABLE webpages ADD COLUMN lease_expires_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE webpages DROP COLUMN lease_expires_at;
+ALTER TABLE webpages DROP COLUMN lease_owner;
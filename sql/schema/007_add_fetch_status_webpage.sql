@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE webpages ADD COLUMN last_fetch_status TEXT;
+ALTER TABLE webpages ADD COLUMN last_fetch_error TEXT;
+
+-- +goose Down
+ALTER TABLE webpages DROP COLUMN last_fetch_error;
+ALTER TABLE webpages DROP COLUMN last_fetch_status;
@@ -0,0 +1,24 @@
+-- +goose Up
+ALTER TABLE webpages ADD COLUMN max_item_age_days INTEGER;
+ALTER TABLE posts ADD COLUMN starred BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE posts_archive (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT,
+    url TEXT NOT NULL,
+    published_at TIMESTAMP,
+    postName TEXT,
+    webpage_id UUID,
+    archived_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX posts_published_or_created_idx ON posts ((COALESCE(published_at, created_at)));
+
+-- +goose Down
+DROP INDEX posts_published_or_created_idx;
+DROP TABLE posts_archive;
+ALTER TABLE posts DROP COLUMN starred;
+ALTER TABLE webpages DROP COLUMN max_item_age_days;
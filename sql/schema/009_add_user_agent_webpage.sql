@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE webpages ADD COLUMN user_agent TEXT;
+
+-- +goose Down
+ALTER TABLE webpages DROP COLUMN user_agent;
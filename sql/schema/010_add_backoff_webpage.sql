@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE webpages ADD COLUMN backoff_until TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE webpages DROP COLUMN backoff_until;
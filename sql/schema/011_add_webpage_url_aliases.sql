@@ -0,0 +1,36 @@
+-- +goose Up
+CREATE TABLE webpage_url_aliases (
+    url TEXT PRIMARY KEY,
+    webpage_id UUID NOT NULL REFERENCES webpages(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX webpage_url_aliases_webpage_id_idx ON webpage_url_aliases (webpage_id);
+
+ALTER TABLE webpages ADD COLUMN redirect_url TEXT;
+ALTER TABLE webpages ADD COLUMN redirect_count INTEGER NOT NULL DEFAULT 0;
+
+-- A feed that has moved keeps its old URL as an alias, and adding that URL
+-- again must fail just like adding the current URL twice.
+-- +goose StatementBegin
+CREATE FUNCTION webpages_reject_alias_url() RETURNS trigger AS $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM webpage_url_aliases WHERE url = NEW.url AND webpage_id <> NEW.id) THEN
+        RAISE EXCEPTION 'feed URL % has moved to another webpage', NEW.url
+            USING ERRCODE = 'unique_violation', CONSTRAINT = 'webpage_url_aliases_pkey';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER webpages_reject_alias_url
+BEFORE INSERT OR UPDATE OF url ON webpages
+FOR EACH ROW EXECUTE FUNCTION webpages_reject_alias_url();
+
+-- +goose Down
+DROP TRIGGER webpages_reject_alias_url ON webpages;
+DROP FUNCTION webpages_reject_alias_url();
+ALTER TABLE webpages DROP COLUMN redirect_count;
+ALTER TABLE webpages DROP COLUMN redirect_url;
+DROP TABLE webpage_url_aliases;
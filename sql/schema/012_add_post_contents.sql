@@ -0,0 +1,27 @@
+-- +goose Up
+ALTER TABLE webpages ADD COLUMN full_text BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Full article text extracted from the post's page, for webpages whose
+-- feeds only carry a summary. A row is queued as pending when the post is
+-- created and filled in by the content worker.
+CREATE TABLE post_contents (
+    post_id UUID PRIMARY KEY REFERENCES posts(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    content TEXT,
+    content_text TEXT,
+    word_count INTEGER NOT NULL DEFAULT 0,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    -- retry_at keeps the row from being claimed: until the lease of the
+    -- worker fetching it ends, or until it is due to be retried.
+    retry_at TIMESTAMP,
+    fetched_at TIMESTAMP
+);
+
+CREATE INDEX post_contents_queue_idx ON post_contents (created_at) WHERE status IN ('pending', 'fetching');
+
+-- +goose Down
+DROP TABLE post_contents;
+ALTER TABLE webpages DROP COLUMN full_text;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- Descriptions are sanitized on ingest; description_text is their plain
+-- text. Rows ingested before this migration have no plain text and are
+-- sanitized when they are read.
+ALTER TABLE posts ADD COLUMN description_text TEXT;
+ALTER TABLE posts_archive ADD COLUMN description_text TEXT;
+
+-- +goose Down
+ALTER TABLE posts_archive DROP COLUMN description_text;
+ALTER TABLE posts DROP COLUMN description_text;
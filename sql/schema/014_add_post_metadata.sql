@@ -0,0 +1,21 @@
+-- +goose Up
+-- Categories are a JSON array of strings and enclosures a JSON array of
+-- {"url", "type", "length"} objects, both as found in the feed.
+ALTER TABLE posts ADD COLUMN author TEXT;
+ALTER TABLE posts ADD COLUMN categories JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE posts ADD COLUMN enclosures JSONB NOT NULL DEFAULT '[]';
+
+ALTER TABLE posts_archive ADD COLUMN author TEXT;
+ALTER TABLE posts_archive ADD COLUMN categories JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE posts_archive ADD COLUMN enclosures JSONB NOT NULL DEFAULT '[]';
+
+CREATE INDEX posts_webpage_published_or_created_idx ON posts (webpage_id, (COALESCE(published_at, created_at)), id);
+
+-- +goose Down
+DROP INDEX posts_webpage_published_or_created_idx;
+ALTER TABLE posts_archive DROP COLUMN enclosures;
+ALTER TABLE posts_archive DROP COLUMN categories;
+ALTER TABLE posts_archive DROP COLUMN author;
+ALTER TABLE posts DROP COLUMN enclosures;
+ALTER TABLE posts DROP COLUMN categories;
+ALTER TABLE posts DROP COLUMN author;
@@ -0,0 +1,31 @@
+-- +goose Up
+-- Folders organise webpages. A webpage can be in several folders, so they
+-- double as tags, and folders nest like OPML outlines.
+CREATE TABLE folders (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT NOT NULL,
+    parent_id UUID REFERENCES folders(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX folders_parent_name_idx ON folders (COALESCE(parent_id, '00000000-0000-0000-0000-000000000000'::uuid), lower(name));
+
+CREATE TABLE webpage_folders (
+    webpage_id UUID NOT NULL REFERENCES webpages(id) ON DELETE CASCADE,
+    folder_id UUID NOT NULL REFERENCES folders(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (webpage_id, folder_id)
+);
+
+CREATE INDEX webpage_folders_folder_id_idx ON webpage_folders (folder_id);
+
+ALTER TABLE posts ADD COLUMN read BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX posts_unread_webpage_id_idx ON posts (webpage_id) WHERE NOT read;
+
+-- +goose Down
+DROP INDEX posts_unread_webpage_id_idx;
+ALTER TABLE posts DROP COLUMN read;
+DROP TABLE webpage_folders;
+DROP TABLE folders;
@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN score DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+CREATE INDEX posts_score_idx ON posts (score DESC, id) WHERE score > 0;
+
+-- +goose Down
+DROP INDEX posts_score_idx;
+ALTER TABLE posts DROP COLUMN score;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- Posts covering the same story share a cluster_id. simhash fingerprints
+-- the title and url_key is the normalized URL; both are set when a post is
+-- clustered, so a NULL cluster_id marks a post still waiting for it.
+ALTER TABLE posts ADD COLUMN cluster_id UUID;
+ALTER TABLE posts ADD COLUMN simhash BIGINT;
+ALTER TABLE posts ADD COLUMN url_key TEXT;
+
+CREATE INDEX posts_cluster_id_idx ON posts (cluster_id);
+CREATE INDEX posts_url_key_idx ON posts (url_key);
+CREATE INDEX posts_unclustered_idx ON posts (created_at) WHERE cluster_id IS NULL;
+
+-- +goose Down
+DROP INDEX posts_unclustered_idx;
+DROP INDEX posts_url_key_idx;
+DROP INDEX posts_cluster_id_idx;
+ALTER TABLE posts DROP COLUMN url_key;
+ALTER TABLE posts DROP COLUMN simhash;
+ALTER TABLE posts DROP COLUMN cluster_id;
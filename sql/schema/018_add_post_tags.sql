@@ -0,0 +1,31 @@
+-- +goose Up
+-- Tags come from the feed's own categories or from keywords extracted from
+-- the title and description. tagged_at is NULL until a post has been
+-- through the tagger.
+CREATE TABLE post_tags (
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    source TEXT NOT NULL,
+    score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (post_id, tag)
+);
+
+CREATE INDEX post_tags_tag_idx ON post_tags (tag);
+
+-- term_documents counts the tagged posts each term appears in, the
+-- document frequencies keyword extraction weighs terms by.
+CREATE TABLE term_documents (
+    term TEXT PRIMARY KEY,
+    documents INTEGER NOT NULL
+);
+
+ALTER TABLE posts ADD COLUMN tagged_at TIMESTAMP;
+
+CREATE INDEX posts_untagged_idx ON posts (created_at) WHERE tagged_at IS NULL;
+
+-- +goose Down
+DROP INDEX posts_untagged_idx;
+ALTER TABLE posts DROP COLUMN tagged_at;
+DROP TABLE term_documents;
+DROP TABLE post_tags;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- post_terms records the terms each tagged post added to term_documents,
+-- so they can be taken off again when the post is removed or re-tagged.
+CREATE TABLE post_terms (
+    post_id UUID PRIMARY KEY REFERENCES posts(id) ON DELETE CASCADE,
+    terms JSONB NOT NULL
+);
+
+-- Posts tagged so far have no recorded terms, so the document frequencies
+-- are rebuilt by tagging everything again.
+DELETE FROM post_tags;
+DELETE FROM term_documents;
+UPDATE posts SET tagged_at = NULL WHERE tagged_at IS NOT NULL;
+
+-- +goose Down
+DROP TABLE post_terms;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- site_url is the website a feed belongs to, taken from the feed's channel
+-- link on each successful fetch.
+ALTER TABLE webpages ADD COLUMN site_url TEXT;
+
+-- +goose Down
+ALTER TABLE webpages DROP COLUMN site_url;
@@ -0,0 +1,37 @@
+// Package schema embeds the goose migrations so the binary knows which
+// schema version it was built against.
+package schema
+
+import (
+	"embed"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var FS embed.FS
+
+// LatestVersion returns the highest migration version shipped in FS.
+func LatestVersion() int64 {
+	entries, err := fs.ReadDir(FS, ".")
+	if err != nil {
+		return 0
+	}
+
+	var latest int64
+	for _, entry := range entries {
+		prefix, _, ok := strings.Cut(entry.Name(), "_")
+		if !ok {
+			continue
+		}
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			continue
+		}
+		if version > latest {
+			latest = version
+		}
+	}
+	return latest
+}